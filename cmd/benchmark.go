@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/benchmark"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func runBenchmark(args []string) error {
+	flags := newCommandFlags("bench")
+	collectionName := flags.collection()
+	queriesPath := flags.String("queries", "", "vector file the searches cycle through")
+	vectorsPath := flags.String("vectors", "", "vector file the inserts cycle through, defaults to -queries")
+	maxVectors := flags.Int("max-vectors", 0, "only load the first vectors of each file, all of them when zero")
+	duration := flags.Duration("duration", 30*time.Second, "how long to send requests, zero to only stop after -requests")
+	requests := flags.Int("requests", 0, "stop after this many requests, zero for no limit")
+	qps := flags.Float64("qps", 0, "target requests per second, zero to send requests back to back")
+	concurrency := flags.Int("concurrency", 0, "requests in flight, 1 by default or 64 with -qps")
+	insertRatio := flags.Float64("insert-ratio", 0, "fraction of the requests that are inserts")
+	insertBatchSize := flags.Int("insert-batch-size", 1, "documents per insert")
+	insertIDStart := flags.Uint64("insert-id-start", 1<<40, "first doc id inserted, ids increase from there")
+	topK := flags.Uint("top-k", 10, "number of results per search")
+	efConstruction := flags.Uint("ef-construction", 100, "size of the candidate list explored by the search")
+	recordMetrics := flags.Bool("record-metrics", false, "ask the server for the pages each search accessed")
+	userID := flags.String("user-id", "0", "user the requests are made for, a decimal number or a UUID")
+	seed := flags.Int64("seed", 1, "seed of the mix of searches and inserts")
+	label := flags.String("label", "", "free text stored in the report, such as the server version")
+	reportPath := flags.String("report", "", "write the report to this .json file, or append it to this .csv file")
+	flags.Parse(args)
+	if err := flags.require("collection"); err != nil {
+		return err
+	}
+	if *queriesPath == "" && *vectorsPath == "" {
+		return fmt.Errorf("bench: -queries or -vectors is required")
+	}
+	if *vectorsPath == "" {
+		*vectorsPath = *queriesPath
+	}
+	if *reportPath != "" {
+		if ext := strings.ToLower(filepath.Ext(*reportPath)); ext != ".json" && ext != ".csv" {
+			return fmt.Errorf("bench: -report must be a .json or .csv file, got %q", *reportPath)
+		}
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+	user, err := muopdbclient.ParseDocID(*userID)
+	if err != nil {
+		return fmt.Errorf("invalid -user-id: %w", err)
+	}
+
+	var workload benchmark.Workload
+	if *insertRatio < 1 {
+		if workload.Queries, err = loadQueries(*queriesPath, *maxVectors); err != nil {
+			return err
+		}
+	}
+	if *insertRatio > 0 {
+		if workload.Vectors, err = loadQueries(*vectorsPath, *maxVectors); err != nil {
+			return err
+		}
+	}
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := benchmark.Run(ctx, muopdbClient, benchmark.Options{
+		CollectionName:  *collectionName,
+		Duration:        *duration,
+		Requests:        *requests,
+		QPS:             *qps,
+		Concurrency:     *concurrency,
+		InsertRatio:     *insertRatio,
+		InsertBatchSize: *insertBatchSize,
+		InsertIDStart:   *insertIDStart,
+		TopK:            uint32(*topK),
+		EfConstruction:  uint32(*efConstruction),
+		RecordMetrics:   *recordMetrics,
+		UserIds:         []muopdbclient.DocID{user},
+		Seed:            *seed,
+	}, workload)
+	// An interrupted run still reports the requests it completed.
+	if err != nil && !report.Interrupted {
+		return err
+	}
+	report.Label = *label
+
+	printBenchmarkReport(report)
+	if *reportPath != "" {
+		if writeErr := writeBenchmarkReport(*reportPath, report); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+func printBenchmarkReport(report benchmark.Report) {
+	if report.Interrupted {
+		fmt.Println("Interrupted, reporting on the requests completed so far")
+	}
+	fmt.Printf("Ran for %v at %.1f requests/s with %d in flight\n",
+		report.Elapsed.Round(time.Millisecond), report.AchievedQPS, report.Concurrency)
+	for _, op := range report.Operations {
+		fmt.Printf("\n%s: %d requests, %d errors (%.2f%%), %.1f/s\n",
+			op.Operation, op.Requests, op.Errors, 100*op.ErrorRate, op.Throughput)
+		codes := make([]string, 0, len(op.ErrorCodes))
+		for code := range op.ErrorCodes {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Printf("  %s: %d\n", code, op.ErrorCodes[code])
+		}
+		printLatency(op.Latency)
+		if op.PagesAccessed != nil {
+			fmt.Printf("Pages:      mean %.1f, p50 %d, p99 %d, max %d\n",
+				op.PagesAccessed.Mean, op.PagesAccessed.P50, op.PagesAccessed.P99, op.PagesAccessed.Max)
+		}
+	}
+}
+
+// writeBenchmarkReport writes a JSON report, or appends to a CSV file so runs
+// can be compared side by side.
+func writeBenchmarkReport(path string, report benchmark.Report) error {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = report.WriteJSON(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err == nil {
+		err = report.WriteCSV(file, info.Size() == 0)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/configs"
+	"github.com/TrungBui59/test_muopdb/internal/docstore"
+	"github.com/TrungBui59/test_muopdb/internal/embedding"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/vectorfile"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func runCreate(args []string) error {
+	flags := newCommandFlags("create")
+	collectionName := flags.String("collection", "", "name of the collection, required unless the spec names it")
+	spec := flags.collectionSpec()
+	flags.Parse(args)
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+	builder, err := spec.builder(*collectionName)
+	if err != nil {
+		return err
+	}
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	if err := muopdbClient.CreateCollectionFromBuilder(context.Background(), builder); err != nil {
+		return err
+	}
+	fmt.Printf("Created collection %s\n", builder.CollectionName)
+	return nil
+}
+
+func runEmbed(args []string) error {
+	flags := newCommandFlags("embed")
+	input := flags.String("input", "", "text file to embed, one document per line (required)")
+	output := flags.String("output", "", "file the embeddings are written to, as .fvecs, .npy, .jsonl (with the text) or .gob (required)")
+	checkpointPath := flags.String("checkpoint", "", "checkpoint of the completed chunks, defaults to the output path with a .checkpoint suffix")
+	flags.Parse(args)
+	if err := flags.require("input", "output"); err != nil {
+		return err
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+	if *checkpointPath == "" {
+		*checkpointPath = *output + ".checkpoint"
+	}
+
+	// Fail before embedding anything rather than after.
+	if _, err := vectorfile.DetectFormat(*output); err != nil {
+		return err
+	}
+
+	lines, err := openLines(*input)
+	if err != nil {
+		return err
+	}
+	defer lines.Close()
+
+	ctx := context.Background()
+	embedder, err := embedding.New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeEmbedder(embedder)
+
+	writer, err := vectorfile.Create(*output)
+	if err != nil {
+		return err
+	}
+	// Each chunk is written as soon as it and every chunk before it are
+	// embedded, so neither the input nor the embeddings are held in memory.
+	var written int
+	err = streamEmbeddings(ctx, embedder, cfg.EmbeddingConfig, lines, *checkpointPath, func(chunk embedding.Chunk) error {
+		for i, vector := range chunk.Vectors {
+			record := vectorfile.Record{
+				ID:     muopdbclient.NewDocIDFromUint64(uint64(chunk.First + i)),
+				Vector: vector,
+				Text:   chunk.Texts[i],
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		written += len(chunk.Vectors)
+		return nil
+	})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Wrote %d embeddings to %s", written, *output)
+	return os.Remove(*checkpointPath)
+}
+
+func runIngest(args []string) error {
+	flags := newCommandFlags("ingest")
+	collectionName := flags.collection()
+	embeddingsFile := flags.String("embeddings", "", "vector file to insert, .fvecs, .bvecs, .ivecs, .npy, .jsonl or .gob (required)")
+	input := flags.String("input", "", "text file the embeddings were generated from, recorded in the document store when set")
+	create := flags.Bool("create", false, "create the collection first")
+	flush := flags.Bool("flush", true, "flush the collection once every embedding is inserted")
+	flags.Parse(args)
+	if err := flags.require("collection", "embeddings"); err != nil {
+		return err
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+
+	records, err := vectorfile.Open(*embeddingsFile)
+	if err != nil {
+		return err
+	}
+	defer records.Close()
+
+	rows := &recordRows{
+		collectionName: *collectionName,
+		embeddingModel: cfg.EmbeddingConfig.Model,
+		records:        records,
+	}
+	if *input != "" {
+		if cfg.DocStoreConfig.Path == "" {
+			return errors.New("-input needs a document store, set docstore.path")
+		}
+		rows.lines, err = openLines(*input)
+		if err != nil {
+			return err
+		}
+		defer rows.lines.Close()
+	}
+	if cfg.DocStoreConfig.Path != "" {
+		rows.store, err = docstore.Open(cfg.DocStoreConfig.Path)
+		if err != nil {
+			return err
+		}
+		defer rows.store.Close()
+	}
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	if *create {
+		if err := muopdbClient.CreateCollection(context.Background(), *collectionName); err != nil {
+			return err
+		}
+	}
+
+	if err := insertAllDocuments(muopdbClient, *collectionName, rows); err != nil {
+		return err
+	}
+	if rows.unstored > 0 {
+		log.Printf("No document store is configured, the text of %d records is not recorded", rows.unstored)
+	}
+
+	if *flush {
+		// Flush once at the end so the whole ingest lands in a single segment
+		_, err := muopdbClient.Flush(context.Background(), muopdbclient.FlushRequest{
+			CollectionName: *collectionName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertAllDocuments(muopdbClient muopdbclient.MuopDbClient, collectionName string, rows *recordRows) error {
+	inserter, err := muopdbclient.NewBulkInserter(muopdbClient, collectionName,
+		muopdbclient.WithProgressFunc(func(progress muopdbclient.BulkInsertProgress) {
+			rows.inserted(progress)
+			if progress.BatchErr != nil {
+				log.Printf("Error inserting batch %d: %v", progress.Batch, progress.BatchErr)
+				return
+			}
+			log.Printf("Inserted batch %d, %d rows done", progress.Batch, progress.RowsDone)
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	summary, err := inserter.Run(context.Background(), rows)
+	// Record the documents of the batches that made it even when others failed.
+	if storeErr := rows.finish(); storeErr != nil {
+		return errors.Join(err, storeErr)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Finished inserting %d embeddings in %v (%.1f docs/s)",
+		summary.DocsInserted, summary.Elapsed, summary.DocsPerSecond)
+	return nil
+}
+
+func runSearch(args []string) error {
+	flags := newCommandFlags("search")
+	collectionName := flags.collection()
+	query := flags.String("query", "", "text to search for (required)")
+	topK := flags.Uint("top-k", 10, "number of results")
+	efConstruction := flags.Uint("ef-construction", 100, "size of the candidate list explored by the search")
+	userID := flags.String("user-id", "0", "user whose documents are searched, a decimal number or a UUID")
+	flags.Parse(args)
+	if err := flags.require("collection", "query"); err != nil {
+		return err
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+	user, err := muopdbclient.ParseDocID(*userID)
+	if err != nil {
+		return fmt.Errorf("invalid -user-id: %w", err)
+	}
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	embedder, err := embedding.New(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer closeEmbedder(embedder)
+
+	queryVector, err := embedder.EmbedOne(context.Background(), *query)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	searchResponse, err := muopdbClient.Search(context.Background(), muopdbclient.SearchRequest{
+		CollectionName: *collectionName,
+		Vector:         queryVector,
+		TopK:           uint32(*topK),
+		EfConstruction: uint32(*efConstruction),
+		UserIds:        []muopdbclient.DocID{user},
+	})
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	// Read back the raw data to print the responses
+	docs := map[muopdbclient.DocID]docstore.Document{}
+	if cfg.DocStoreConfig.Path != "" {
+		store, err := docstore.Open(cfg.DocStoreConfig.Path)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		docs, err = store.GetMany(*collectionName, searchResponse.DocIds)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Time taken for search: %v seconds\n", elapsed.Seconds())
+	fmt.Printf("Number of results: %d\n", len(searchResponse.DocIds))
+	fmt.Println("================")
+	for i, id := range searchResponse.DocIds {
+		var score float32
+		if i < len(searchResponse.Scores) {
+			score = searchResponse.Scores[i]
+		}
+		doc, ok := docs[id]
+		if !ok {
+			fmt.Printf("RESULT: %s (score %.4f, unknown document)\n", id, score)
+			continue
+		}
+		fmt.Printf("RESULT: %s (score %.4f)\n", doc.Text, score)
+	}
+	fmt.Println("================")
+	return nil
+}
+
+func runFlush(args []string) error {
+	flags := newCommandFlags("flush")
+	collectionName := flags.collection()
+	flags.Parse(args)
+	if err := flags.require("collection"); err != nil {
+		return err
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	response, err := muopdbClient.Flush(context.Background(), muopdbclient.FlushRequest{
+		CollectionName: *collectionName,
+	})
+	if err != nil {
+		return err
+	}
+	printSegments("Flushed segments", response.FlushedSegments)
+	return nil
+}
+
+func runSegments(args []string) error {
+	flags := newCommandFlags("segments")
+	collectionName := flags.collection()
+	flags.Parse(args)
+	if err := flags.require("collection"); err != nil {
+		return err
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	response, err := muopdbClient.GetSegments(context.Background(), muopdbclient.GetSegmentsRequest{
+		CollectionName: *collectionName,
+	})
+	if err != nil {
+		return err
+	}
+	printSegments("Segments", response.SegmentNames)
+	return nil
+}
+
+func runCompact(args []string) error {
+	flags := newCommandFlags("compact")
+	collectionName := flags.collection()
+	segments := flags.String("segments", "", "comma separated segments to compact, every segment when empty")
+	flags.Parse(args)
+	if err := flags.require("collection"); err != nil {
+		return err
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	var response muopdbclient.CompactSegmentsResponse
+	if *segments == "" {
+		response, err = muopdbClient.CompactAllSegments(context.Background(), *collectionName)
+	} else {
+		response, err = muopdbClient.CompactSegments(context.Background(), muopdbclient.CompactSegmentsRequest{
+			CollectionName: *collectionName,
+			SegmentNames:   strings.Split(*segments, ","),
+		})
+	}
+	if err != nil {
+		return err
+	}
+	printSegments("Compacted segments", response.CompactedSegments)
+	return nil
+}
+
+func printSegments(title string, segments []string) {
+	fmt.Printf("%s (%d):\n", title, len(segments))
+	for _, segment := range segments {
+		fmt.Printf("  %s\n", segment)
+	}
+}
+
+func runConfig(args []string) error {
+	flags := newCommandFlags("config")
+	listEnv := flags.Bool("env", false, "list the environment variable overriding each config key instead")
+	flags.Parse(args)
+
+	if *listEnv {
+		envVars := configs.EnvVars()
+		keys := make([]string, 0, len(envVars))
+		for key := range envVars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("%-40s %s\n", key, strings.Join(envVars[key], " or "))
+		}
+		return nil
+	}
+
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+	fmt.Print(cfg)
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/evaluation"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/vectorfile"
+	"log"
+	"os"
+	"time"
+)
+
+func runEvaluate(args []string) error {
+	flags := newCommandFlags("eval")
+	collectionName := flags.collection()
+	basePath := flags.String("base", "", "vector file the collection was built from, to compute the exact neighbors")
+	queriesPath := flags.String("queries", "", "vector file of the queries (required)")
+	groundTruthPath := flags.String("ground-truth", "", "precomputed neighbors, e.g. a benchmark .ivecs file, instead of -base")
+	k := flags.Uint("k", 10, "number of neighbors to compare")
+	metricName := flags.String("metric", string(evaluation.MetricL2), "distance of the collection, l2 or dot")
+	efConstruction := flags.Uint("ef-construction", 100, "size of the candidate list explored by the search")
+	userID := flags.String("user-id", "0", "user whose documents are searched, a decimal number or a UUID")
+	maxQueries := flags.Int("max-queries", 0, "only run the first queries, all of them when zero")
+	reportPath := flags.String("report", "", "also write the report as JSON to this file")
+	flags.Parse(args)
+	if err := flags.require("collection", "queries"); err != nil {
+		return err
+	}
+	if (*basePath == "") == (*groundTruthPath == "") {
+		return errors.New("eval: exactly one of -base and -ground-truth is required")
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+	metric, err := evaluation.ParseMetric(*metricName)
+	if err != nil {
+		return err
+	}
+	user, err := muopdbclient.ParseDocID(*userID)
+	if err != nil {
+		return fmt.Errorf("invalid -user-id: %w", err)
+	}
+
+	queries, err := loadQueries(*queriesPath, *maxQueries)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	truth, err := groundTruth(*basePath, *groundTruthPath, queries, int(*k), metric)
+	if err != nil {
+		return err
+	}
+	log.Printf("Computed the exact neighbors of %d queries in %v", len(queries), time.Since(start))
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	report, err := evaluation.Evaluate(context.Background(), muopdbClient, evaluation.SearchOptions{
+		CollectionName: *collectionName,
+		TopK:           uint32(*k),
+		EfConstruction: uint32(*efConstruction),
+		UserIds:        []muopdbclient.DocID{user},
+	}, queries, truth)
+	if err != nil {
+		return err
+	}
+
+	printReport(report)
+	if *reportPath != "" {
+		return writeJSONFile(*reportPath, report)
+	}
+	return nil
+}
+
+func loadQueries(path string, limit int) ([][]float32, error) {
+	records, err := vectorfile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && limit < len(records) {
+		records = records[:limit]
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s holds no queries", path)
+	}
+	return vectorfile.Vectors(records), nil
+}
+
+// groundTruth reads the precomputed neighbors when given, and otherwise
+// computes them from the base file.
+func groundTruth(basePath, groundTruthPath string, queries [][]float32, k int,
+	metric evaluation.Metric) ([][]muopdbclient.DocID, error) {
+	if groundTruthPath != "" {
+		return evaluation.LoadGroundTruth(groundTruthPath)
+	}
+
+	base, err := vectorfile.Open(basePath)
+	if err != nil {
+		return nil, err
+	}
+	defer base.Close()
+	return evaluation.GroundTruth(base, queries, k, metric)
+}
+
+func printReport(report evaluation.Report) {
+	fmt.Printf("Queries:    %d (%d failed)\n", report.Queries, report.Errors)
+	fmt.Printf("%-12s%.4f\n", fmt.Sprintf("Recall@%d:", report.K), report.Recall)
+	fmt.Printf("MRR:        %.4f\n", report.MRR)
+	printLatency(report.Latency)
+}
+
+func printLatency(latency evaluation.LatencySummary) {
+	fmt.Printf("Latency:    mean %v, p50 %v, p90 %v, p99 %v, p999 %v, max %v\n",
+		latency.Mean, latency.P50, latency.P90, latency.P99, latency.P999, latency.Max)
+}
+
+func writeJSONFile(path string, v any) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0o644)
+}
@@ -1,65 +1,35 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/gob"
+	"fmt"
 	"github.com/TrungBui59/test_muopdb/internal/configs"
-	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
+	"github.com/TrungBui59/test_muopdb/internal/embedding"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
-	"os"
+	"io"
+	"log"
+	"time"
 )
 
-
-func readSentences(filename string) ([]string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var sentences []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		sentences = append(sentences, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return sentences, nil
-}
-
-func loadEmbeddings(filename string) ([][]float32, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var embeddings [][]float32
-	decoder := gob.NewDecoder(file)
-	err = decoder.Decode(&embeddings)
-	return embeddings, err
-}
-
-func saveEmbeddings(filename string, embeddings [][]float32) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := gob.NewEncoder(file)
-	return encoder.Encode(embeddings)
-}
-
-func createGRPCClientConn(serverAddress string) (*grpc.ClientConn, error) {
+func createGRPCClientConn(cfg configs.MuopDBConfig) (*grpc.ClientConn, error) {
 	// Create a connection to the server
 	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	}
+	opts = append(opts, muopdbclient.RetryDialOptions(muopdbclient.RetryPolicy{
+		MaxAttempts:       cfg.Retry.MaxAttempts,
+		InitialBackoff:    time.Duration(cfg.Retry.InitialBackoffMs) * time.Millisecond,
+		MaxBackoff:        time.Duration(cfg.Retry.MaxBackoffMs) * time.Millisecond,
+		BackoffMultiplier: cfg.Retry.BackoffMultiplier,
+		Jitter:            cfg.Retry.Jitter,
+		BudgetMaxTokens:   cfg.Retry.BudgetMaxTokens,
+		BudgetTokenRatio:  cfg.Retry.BudgetTokenRatio,
+		RetryInserts:      cfg.Retry.RetryInserts,
+	})...)
+
+	serverAddress := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	conn, err := grpc.NewClient(serverAddress, opts...)
 	if err != nil {
 		return nil, err
@@ -68,42 +38,32 @@ func createGRPCClientConn(serverAddress string) (*grpc.ClientConn, error) {
 	return conn, nil
 }
 
-func createGeminiClient(cfg configs.Config) (*genai.Client, error) {
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.GeminiConfig.APIKey))
-	if err != nil {
-		return nil, err
-	}
-	return client, nil
-}
-
-func generateEmbedding(client *genai.Client, file string, model string) ([][]float32, error) {
-	embedding := client.EmbeddingModel(model)
-
-	ctx := context.Background()
-
-	files, err := os.Open(file)
-	if err != nil {
-		return nil, err
+// closeEmbedder releases the resources held by embedders that have any.
+func closeEmbedder(embedder embedding.Embedder) {
+	if cached, ok := embedder.(*embedding.CachedEmbedder); ok {
+		stats := cached.Stats()
+		log.Printf("Embedding cache: %d hits, %d misses, %d evictions, %d entries (%d bytes)",
+			stats.Hits, stats.Misses, stats.Evictions, stats.Entries, stats.Bytes)
 	}
-	defer files.Close()
 
-	scanner := bufio.NewScanner(files)
-	batchContent := embedding.NewBatch()
-	for scanner.Scan() {
-		text := scanner.Text()
-		batchContent.AddContent(genai.Text(text))
+	closer, ok := embedder.(io.Closer)
+	if !ok {
+		return
 	}
-
-	res, err := embedding.BatchEmbedContents(ctx, batchContent)
-	if err != nil {
-		return nil, err
-	}
-
-	var result [][]float32
-	for _, embedding := range res.Embeddings {
-		result = append(result, embedding.Values)
+	if err := closer.Close(); err != nil {
+		log.Printf("Error closing embedder: %v", err)
 	}
+}
 
-	return result, nil
+// streamEmbeddings embeds the texts and hands every chunk to emit in input
+// order. Completed chunks are checkpointed next to the output so a failed run
+// resumes where it stopped.
+func streamEmbeddings(ctx context.Context, embedder embedding.Embedder, cfg configs.EmbeddingConfig,
+	texts embedding.TextIterator, checkpointPath string, emit func(embedding.Chunk) error) error {
+	return embedding.Stream(ctx, embedder, texts, embedding.GenerateOptions{
+		ChunkSize:         cfg.BatchSize,
+		Concurrency:       cfg.Concurrency,
+		RequestsPerSecond: cfg.RequestsPerSecond,
+		CheckpointPath:    checkpointPath,
+	}, emit)
 }
@@ -1,211 +1,141 @@
 package main
 
 import (
-	"bytes"
-	"context"
-	"encoding/binary"
+	"flag"
 	"fmt"
-	pb "github.com/TrungBui59/test_muopdb/api/pb"
 	"github.com/TrungBui59/test_muopdb/internal/configs"
 	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
-	"github.com/google/generative-ai-go/genai"
 	"log"
-	"time"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-var (
-	inputSample        = "/home/trungbui/test_muopdb/samples/100_sentence.txt"
-	outputSample       = "/home/trungbui/test_muopdb/samples/100_sentence_embedding.gob"
-	embeddingModelName = "text-embedding-004"
-	collectionName     = "test-collection-27"
-)
-
-func demoGenerateEmbedding(inputSampleFile, outputSampleFile, embeddingModel string,
-	cfg configs.Config) error {
-	geminiClient, err := createGeminiClient(cfg)
-	if err != nil {
-		panic("Error creating Gemini client: " + err.Error())
-	}
-
-	embedding, err := generateEmbedding(geminiClient,
-		inputSampleFile,
-		embeddingModel,
-	)
-
-	if err != nil {
-		return err
-	}
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-	return saveEmbeddings(outputSampleFile, embedding)
+var commands = []command{
+	{name: "create", summary: "create a collection", run: runCreate},
+	{name: "embed", summary: "embed every line of a text file", run: runEmbed},
+	{name: "ingest", summary: "insert embeddings into a collection", run: runIngest},
+	{name: "search", summary: "search a collection with a text query", run: runSearch},
+	{name: "flush", summary: "flush the pending writes of a collection", run: runFlush},
+	{name: "segments", summary: "list the segments of a collection", run: runSegments},
+	{name: "compact", summary: "compact the segments of a collection", run: runCompact},
+	{name: "eval", summary: "measure recall, MRR and latency against brute-force neighbors", run: runEvaluate},
+	{name: "bench", summary: "load test searches and inserts and report latencies", run: runBenchmark},
+	{name: "sweep", summary: "compare collection configurations on recall, latency and build time", run: runSweep},
+	{name: "serve", summary: "serve the REST API", run: runServe},
+	{name: "config", summary: "print the effective config with secrets redacted", run: runConfig},
 }
 
-func insertAllDocuments(muopdbClient muopdbclient.MuopDbClient, collectionName string, embeddings [][]float32) error {
-	var (
-		batchSize       = 5
-		totalEmbeddings = len(embeddings)
-		startIdx        = 0
-	)
-	conn, err := createGRPCClientConn(fmt.Sprintf("localhost:9002"))
-	if err != nil {
-		return err
+func usage() {
+	program := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", program)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
 	}
-	defer conn.Close()
-
-	client := pb.NewIndexServerClient(conn)
-
-	startTime := time.Now()
-	for startIdx < totalEmbeddings {
-		endIdx := min(startIdx+batchSize, totalEmbeddings)
-		batchEmbeddings := embeddings[startIdx:endIdx]
-
-		// Flatten the batch embeddings into a single vector
-		var vectors []float32
-		ids := make([][]byte, len(batchEmbeddings))
-		for idx, embedding := range batchEmbeddings {
-			vectors = append(vectors, embedding...)
-			//ids = append(ids, uint64(startIdx+idx))
-			ids[idx] = make([]byte, 16)
-			binary.LittleEndian.PutUint64(ids[idx], uint64(idx+startIdx))
-		}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", program)
+}
 
-		// Create the insert request
-		request := muopdbclient.InsertRequest{
-			CollectionName: collectionName,
-			DocIds:         ids,
-			Vectors:        vectors,
-			UserIds:        make([][]byte, 1),
-		}
+// overrideFlag collects repeated -set key=value flags.
+type overrideFlag map[string]string
 
-		//Send the insert request
-		if _, err := muopdbClient.Insert(context.Background(), request); err != nil {
-			log.Printf("Error inserting batch [%d:%d]: %v", startIdx, endIdx, err)
-			return err
-		}
+func (o overrideFlag) String() string {
+	return ""
+}
 
-		log.Printf("Inserted batch [%d:%d]", startIdx, endIdx)
-		startIdx = endIdx
+func (o overrideFlag) Set(value string) error {
+	key, raw, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
 	}
-
-	elapsed := time.Since(startTime)
-	log.Printf("Finished inserting %d embeddings in %v", totalEmbeddings, elapsed)
-
-	client.Flush(context.TODO(), &pb.FlushRequest{
-		CollectionName: collectionName,
-	})
-
+	o[key] = raw
 	return nil
 }
 
-func getEmbedding(client *genai.Client, model, prompt string) ([]float32, error) {
-	embedding := client.EmbeddingModel(model)
-	ctx := context.Background()
-	res, err := embedding.EmbedContent(ctx, genai.Text(prompt))
-	if err != nil {
-		return nil, err
-	}
-	return res.Embedding.Values, nil
+// commandFlags is the flag set of a command, with the flags every command
+// shares.
+type commandFlags struct {
+	*flag.FlagSet
+	configPath string
+	overrides  overrideFlag
 }
 
-func demoInsertEmbedding(cfg configs.Config, collectionName, outputEmbeddingFile string) error {
-	//Configure logging
-	conn, err := createGRPCClientConn(fmt.Sprintf("%s:%d", cfg.MuopDBConfig.Host, cfg.MuopDBConfig.Port))
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
-
-	muopdbClient := muopdbclient.NewClient(conn)
-
-	//// Load the embeddings from the .gob file
-	embeddings, err := loadEmbeddings(outputEmbeddingFile)
-	if err != nil {
-		return err
-	}
-
-	//create collection
-	err = muopdbClient.CreateCollection(
-		context.TODO(),
-		collectionName,
-	)
-
-	if err != nil {
-		return err
+func newCommandFlags(name string) *commandFlags {
+	f := &commandFlags{
+		FlagSet:   flag.NewFlagSet(name, flag.ExitOnError),
+		overrides: overrideFlag{},
 	}
-
-	// Insert the embeddings into MuopDB
-	return insertAllDocuments(muopdbClient, collectionName, embeddings)
+	f.StringVar(&f.configPath, "config", "", "path of a config file layered over the embedded defaults")
+	f.Var(f.overrides, "set", "override a config key, e.g. -set muopdb.host=10.0.0.2 (repeatable, applied after environment variables)")
+	return f
 }
 
-func demoSearch(cfg configs.Config, collectionName string) error {
-	conn, err := createGRPCClientConn(fmt.Sprintf("%s:%d", cfg.MuopDBConfig.Host, cfg.MuopDBConfig.Port))
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
+func (f *commandFlags) collection() *string {
+	return f.String("collection", "", "name of the collection (required)")
+}
 
-	muopdbClient := muopdbclient.NewClient(conn)
-	geminiClient, err := createGeminiClient(cfg)
+func (f *commandFlags) config() (configs.Config, error) {
+	cfg, err := configs.NewConfig(f.configPath, f.overrides)
 	if err != nil {
-		log.Fatalf("Error creating Gemini client: %v", err)
+		return configs.Config{}, fmt.Errorf("loading config: %w", err)
 	}
+	return cfg, nil
+}
 
-	query := "Space Science Fiction"
-	queryVector, err := getEmbedding(geminiClient, embeddingModelName, query)
-	if err != nil {
-		return err
+// require fails when one of the named flags was left empty.
+func (f *commandFlags) require(names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if f.Lookup(name).Value.String() == "" {
+			missing = append(missing, "-"+name)
+		}
 	}
-
-	// Read back the raw data to print the responses
-	sentences, err := readSentences(inputSample)
-	if err != nil {
-		return err
+	if len(missing) > 0 {
+		return fmt.Errorf("%s: missing required flags %s", f.Name(), strings.Join(missing, ", "))
 	}
+	return nil
+}
 
-	start := time.Now()
-	searchResponse, err := muopdbClient.Search(context.TODO(), muopdbclient.SearchRequest{
-		CollectionName: collectionName,
-		Vector:         queryVector,
-		TopK:           10,
-		EfConstruction: 100,
-		RecordMetrics:  false,
-		UserIds:        [][]byte{make([]byte, 16)},
-	})
-
-	end := time.Now()
-
-	fmt.Printf("Response: %v\n", searchResponse)
-
+func connect(cfg configs.Config) (muopdbclient.MuopDbClient, error) {
+	conn, err := createGRPCClientConn(cfg.MuopDBConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return muopdbclient.NewClient(conn), nil
+}
 
-	fmt.Printf("Time taken for search: %v seconds\n", end.Sub(start).Seconds())
-
-	fmt.Printf("Number of results: %d\n", len(searchResponse.DocIds))
-	fmt.Println("================")
-	var docID int32
-	for _, id := range searchResponse.DocIds {
-		// Assuming the ID is a byte slice and converting it to an integer
-		buffer := bytes.NewReader(id)
-		binary.Read(buffer, binary.LittleEndian, &docID)
-		fmt.Printf("RESULT: %s\n", sentences[docID])
+func closeClient(client muopdbclient.MuopDbClient) {
+	if err := client.Close(); err != nil {
+		log.Printf("Error closing MuopDB client: %v", err)
 	}
-	fmt.Println("================")
-	return nil
 }
+
 func main() {
-	cfg, err := configs.NewConfig("")
-	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	if name := os.Args[1]; name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		usage()
+		return
 	}
 
-	//err = demoInsertEmbedding(cfg, collectionName, outputSample)
-	//if err != nil {
-	//	log.Fatalf("Error inserting embedding: %v\n", err)
-	//}
-
-	err = demoSearch(cfg, collectionName)
-	if err != nil {
-		log.Fatalf("Error searching for document: %d\n", err)
+	for _, cmd := range commands {
+		if cmd.name != os.Args[1] {
+			continue
+		}
+		if err := cmd.run(os.Args[2:]); err != nil {
+			log.Fatalf("Error running %s: %v", cmd.name, err)
+		}
+		return
 	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
 }
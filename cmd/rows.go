@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/docstore"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/vectorfile"
+	"io"
+	"os"
+	"sync"
+)
+
+// documentBatchSize is the number of documents recorded per document store
+// transaction while ingesting.
+const documentBatchSize = 1000
+
+// lineReader streams the lines of a text file, counting them for error
+// messages.
+type lineReader struct {
+	path    string
+	file    *os.File
+	scanner *bufio.Scanner
+	line    int
+}
+
+func openLines(path string) (*lineReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	// Documents can be far longer than the default 64KB line limit.
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	return &lineReader{path: path, file: file, scanner: scanner}, nil
+}
+
+// Next returns the next line, or io.EOF after the last one.
+func (l *lineReader) Next() (string, error) {
+	if !l.scanner.Scan() {
+		if err := l.scanner.Err(); err != nil {
+			return "", fmt.Errorf("%s:%d: %w", l.path, l.line+1, err)
+		}
+		return "", io.EOF
+	}
+	l.line++
+	return l.scanner.Text(), nil
+}
+
+func (l *lineReader) Close() error {
+	return l.file.Close()
+}
+
+// recordRows feeds the records of a vector file to a BulkInserter one at a
+// time. The text of each record, from the record itself or from the matching
+// line of a text file, is recorded in the document store once the batch
+// holding the record was inserted, so the store never holds documents MuopDB
+// does not. Neither file is ever held in memory.
+type recordRows struct {
+	collectionName string
+	embeddingModel string
+	records        *vectorfile.Reader
+	// lines and store are optional.
+	lines *lineReader
+	store *docstore.Store
+
+	mu sync.Mutex
+	// row is the number of the next row, as numbered by the BulkInserter.
+	row uint64
+	// inFlight holds the documents of the rows not inserted yet.
+	inFlight map[uint64]docstore.Document
+	// pending holds inserted documents waiting to be recorded.
+	pending  []docstore.Document
+	storeErr error
+	unstored int
+}
+
+func (r *recordRows) Next() (muopdbclient.Row, error) {
+	record, err := r.records.Next()
+	if err != nil {
+		return muopdbclient.Row{}, err
+	}
+
+	source := r.records.Path()
+	if r.lines != nil {
+		text, err := r.lines.Next()
+		if errors.Is(err, io.EOF) {
+			return muopdbclient.Row{}, fmt.Errorf("%s has %d lines, fewer than the records of %s",
+				r.lines.path, r.lines.line, source)
+		}
+		if err != nil {
+			return muopdbclient.Row{}, err
+		}
+		record.Text, source = text, r.lines.path
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record.Text != "" {
+		if r.store == nil {
+			r.unstored++
+		} else {
+			if r.inFlight == nil {
+				r.inFlight = make(map[uint64]docstore.Document)
+			}
+			r.inFlight[r.row] = docstore.Document{
+				ID:             record.ID,
+				Text:           record.Text,
+				Source:         source,
+				EmbeddingModel: r.embeddingModel,
+			}
+		}
+	}
+	r.row++
+
+	return muopdbclient.Row{ID: record.ID, Vector: record.Vector}, nil
+}
+
+// inserted is the progress func of the BulkInserter. The documents of a batch
+// that was inserted, even if flushing it failed, are queued for the store,
+// those of a failed batch are dropped.
+func (r *recordRows) inserted(progress muopdbclient.BulkInsertProgress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for row := progress.FirstRow; row < progress.FirstRow+uint64(progress.NumRows); row++ {
+		doc, ok := r.inFlight[row]
+		if !ok {
+			continue
+		}
+		delete(r.inFlight, row)
+		var flushErr *muopdbclient.FlushError
+		if progress.BatchErr == nil || errors.As(progress.BatchErr, &flushErr) {
+			r.pending = append(r.pending, doc)
+		}
+	}
+	if len(r.pending) >= documentBatchSize {
+		r.flush()
+	}
+}
+
+// finish records the inserted documents still queued and returns the first
+// error of the document store.
+func (r *recordRows) finish() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flush()
+	return r.storeErr
+}
+
+func (r *recordRows) flush() {
+	if len(r.pending) == 0 || r.storeErr != nil {
+		return
+	}
+	if err := r.store.Put(r.collectionName, r.pending...); err != nil {
+		r.storeErr = fmt.Errorf("recording documents: %w", err)
+		return
+	}
+	r.pending = r.pending[:0]
+}
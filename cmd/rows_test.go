@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	pb "github.com/TrungBui59/test_muopdb/api/pb"
+	"github.com/TrungBui59/test_muopdb/internal/docstore"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"github.com/TrungBui59/test_muopdb/internal/vectorfile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// ingestRecords inserts 5 records, flushing after every insert, into a server
+// failing the calls of the methods in fail.
+func ingestRecords(t *testing.T, fail ...string) (*muopdbtest.Server, *docstore.Store, []muopdbclient.DocID, error) {
+	t.Helper()
+	dir := t.TempDir()
+
+	var ids []muopdbclient.DocID
+	var records []vectorfile.Record
+	for i := range 5 {
+		id := muopdbclient.NewDocIDFromUint64(uint64(100 + i))
+		ids = append(ids, id)
+		records = append(records, vectorfile.Record{ID: id, Vector: []float32{float32(i), 1}, Text: "text"})
+	}
+	path := filepath.Join(dir, "embeddings.jsonl")
+	if err := vectorfile.Save(path, records); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := vectorfile.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reader.Close() })
+
+	store, err := docstore.Open(filepath.Join(dir, "documents.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	server := muopdbtest.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(fail, info.FullMethod) {
+			return nil, status.Error(codes.Internal, "disk full")
+		}
+		return handler(ctx, req)
+	}))
+	t.Cleanup(server.Close)
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := muopdbclient.NewClient(conn, muopdbclient.WithFlushPolicy(muopdbclient.FlushPolicy{EveryCall: true}))
+	t.Cleanup(func() { client.Close() })
+	if err := client.CreateCollection(context.Background(), "docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := &recordRows{collectionName: "docs", records: reader, store: store}
+	return server, store, ids, insertAllDocuments(client, "docs", rows)
+}
+
+func TestIngestRecordsDocumentsOnceInserted(t *testing.T) {
+	server, store, ids, err := ingestRecords(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := server.IndexServer.NumDocuments("docs"); n != len(ids) {
+		t.Errorf("the server holds %d documents, want %d", n, len(ids))
+	}
+	docs, err := store.GetMany("docs", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != len(ids) {
+		t.Errorf("the store holds %d documents, want %d", len(docs), len(ids))
+	}
+}
+
+func TestIngestSkipsDocumentsOfFailedBatches(t *testing.T) {
+	_, store, ids, err := ingestRecords(t, pb.IndexServer_Insert_FullMethodName, pb.IndexServer_InsertPacked_FullMethodName)
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("ingest returned %v, want the insert error", err)
+	}
+	docs, err := store.GetMany("docs", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 0 {
+		t.Errorf("the store holds %d documents of a failed insert", len(docs))
+	}
+}
+
+func TestIngestRecordsDocumentsOfFailedFlushes(t *testing.T) {
+	server, store, ids, err := ingestRecords(t, pb.IndexServer_Flush_FullMethodName)
+	var flushErr *muopdbclient.FlushError
+	if !errors.As(err, &flushErr) {
+		t.Fatalf("ingest returned %v, want the flush error", err)
+	}
+	if n := server.IndexServer.NumDocuments("docs"); n != len(ids) {
+		t.Fatalf("the server holds %d documents, want %d", n, len(ids))
+	}
+	docs, err := store.GetMany("docs", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != len(ids) {
+		t.Errorf("the store holds %d of the %d documents inserted before their flush failed", len(docs), len(ids))
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"github.com/TrungBui59/test_muopdb/internal/configs"
+	"github.com/TrungBui59/test_muopdb/internal/docstore"
+	"github.com/TrungBui59/test_muopdb/internal/embedding"
+	httpapi "github.com/TrungBui59/test_muopdb/internal/http"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func runServe(args []string) error {
+	flags := newCommandFlags("serve")
+	flags.Parse(args)
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+	return runServer(cfg)
+}
+
+// runServer serves the REST API until SIGINT or SIGTERM, then drains in-flight
+// requests before closing the MuopDB connection.
+func runServer(cfg configs.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := createGRPCClientConn(cfg.MuopDBConfig)
+	if err != nil {
+		return err
+	}
+	muopdbClient := muopdbclient.NewClient(conn)
+	defer closeClient(muopdbClient)
+
+	embedder, err := embedding.New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeEmbedder(embedder)
+
+	// Left nil when disabled, a nil *docstore.Store would not compare equal to nil.
+	var documents httpapi.DocumentStore
+	if cfg.DocStoreConfig.Path != "" {
+		store, err := docstore.Open(cfg.DocStoreConfig.Path)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		documents = store
+	}
+
+	app := httpapi.NewApp(cfg, muopdbClient, embedder, documents)
+	return app.Serve(ctx)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// specField is the flag of one CollectionSpec option. Only flags given on the
+// command line set their field, the others stay nil and keep the value of the
+// spec file or the server default.
+type specField struct {
+	field reflect.Value
+}
+
+func (s specField) String() string {
+	if !s.field.IsValid() || s.field.IsNil() {
+		return ""
+	}
+	return fmt.Sprint(s.field.Elem().Interface())
+}
+
+func (s specField) Set(raw string) error {
+	value := reflect.New(s.field.Type().Elem())
+	switch kind := value.Elem().Kind(); kind {
+	case reflect.String:
+		value.Elem().SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.Elem().SetBool(b)
+	case reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, value.Elem().Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.Elem().SetUint(n)
+	case reflect.Float32:
+		f, err := strconv.ParseFloat(raw, 32)
+		if err != nil {
+			return err
+		}
+		value.Elem().SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported option type %s", kind)
+	}
+	s.field.Set(value)
+	return nil
+}
+
+func (s specField) IsBoolFlag() bool {
+	return s.field.Type().Elem().Kind() == reflect.Bool
+}
+
+// collectionSpecFlags are the -spec flag and a flag per CollectionSpec option,
+// e.g. -num-features for num_features.
+type collectionSpecFlags struct {
+	path  string
+	flags muopdbclient.CollectionSpec
+}
+
+func (f *commandFlags) collectionSpec() *collectionSpecFlags {
+	s := &collectionSpecFlags{}
+	f.StringVar(&s.path, "spec", "", "JSON file of the collection options, as accepted by POST /collections; option flags override it")
+
+	v := reflect.ValueOf(&s.flags).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type.Kind() != reflect.Pointer {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		usage := fmt.Sprintf("collection option %s as a `%s`, the server default when unset", key, field.Type.Elem().Kind())
+		switch key {
+		case "quantization_type":
+			usage += " (NO_QUANTIZER or PRODUCT_QUANTIZER)"
+		case "posting_list_encoding_type":
+			usage += " (PLAIN_ENCODING or ELIAS_FANO)"
+		}
+		f.Var(specField{field: v.Field(i)}, strings.ReplaceAll(key, "_", "-"), usage)
+	}
+	return s
+}
+
+// builder layers the option flags over the spec file. collectionName, when
+// set, replaces the name of the spec file.
+func (s *collectionSpecFlags) builder(collectionName string) (*muopdbclient.CollectionBuilder, error) {
+	var spec muopdbclient.CollectionSpec
+	if s.path != "" {
+		file, err := os.Open(s.path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.path, err)
+		}
+	}
+
+	v, flags := reflect.ValueOf(&spec).Elem(), reflect.ValueOf(s.flags)
+	for i := 0; i < v.NumField(); i++ {
+		if field := flags.Field(i); field.Kind() == reflect.Pointer && !field.IsNil() {
+			v.Field(i).Set(field)
+		}
+	}
+	if collectionName != "" {
+		spec.CollectionName = collectionName
+	}
+	if spec.CollectionName == "" {
+		return nil, fmt.Errorf("no collection name, set -collection or collection_name in the spec")
+	}
+	return spec.Builder()
+}
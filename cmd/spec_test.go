@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollectionSpecFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.json")
+	spec := `{"collection_name": "docs", "num_features": 768, "max_posting_list_size": 100, "quantization_type": "PRODUCT_QUANTIZER"}`
+	if err := os.WriteFile(path, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	flags := newCommandFlags("create")
+	specFlags := flags.collectionSpec()
+	if err := flags.Parse([]string{"-spec", path, "-num-features", "128", "-reindex", "-clustering-distance-threshold-pct", "0.5"}); err != nil {
+		t.Fatal(err)
+	}
+	builder, err := specFlags.builder("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if builder.CollectionName != "docs" {
+		t.Errorf("collection name = %q, want the name of the spec", builder.CollectionName)
+	}
+	if builder.NumFeatures == nil || *builder.NumFeatures != 128 {
+		t.Errorf("num features = %v, want the flag to override the spec", builder.NumFeatures)
+	}
+	if builder.MaxPostingListSize == nil || *builder.MaxPostingListSize != 100 {
+		t.Errorf("max posting list size = %v, want 100 from the spec", builder.MaxPostingListSize)
+	}
+	if builder.Reindex == nil || !*builder.Reindex {
+		t.Errorf("reindex = %v, want true", builder.Reindex)
+	}
+	if builder.ClusteringDistanceThresholdPct == nil || *builder.ClusteringDistanceThresholdPct != 0.5 {
+		t.Errorf("clustering distance threshold = %v, want 0.5", builder.ClusteringDistanceThresholdPct)
+	}
+	if builder.QuantizationType == nil || builder.MaxPendingOps != nil {
+		t.Errorf("quantization type = %v and max pending ops = %v, want only the first set", builder.QuantizationType, builder.MaxPendingOps)
+	}
+
+	if builder, err := specFlags.builder("other"); err != nil || builder.CollectionName != "other" {
+		t.Errorf("builder(\"other\") = %v, %v, want -collection to replace the name of the spec", builder, err)
+	}
+}
+
+func TestCollectionSpecFlagsErrors(t *testing.T) {
+	flags := newCommandFlags("create")
+	flags.Init("create", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	specFlags := flags.collectionSpec()
+	if err := flags.Parse([]string{"-num-features", "-3"}); err == nil {
+		t.Error("a negative -num-features was accepted")
+	}
+	if _, err := specFlags.builder(""); err == nil {
+		t.Error("a spec without a collection name was accepted")
+	}
+
+	path := filepath.Join(t.TempDir(), "spec.json")
+	if err := os.WriteFile(path, []byte(`{"collection_name": "docs", "num_feature": 3}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	specFlags.path = path
+	if _, err := specFlags.builder(""); err == nil {
+		t.Error("a spec with an unknown option was accepted")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/evaluation"
+	"github.com/TrungBui59/test_muopdb/internal/sweep"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func runSweep(args []string) error {
+	flags := newCommandFlags("sweep")
+	specPath := flags.String("spec", "", "YAML file describing the configurations to try (required)")
+	reportPath := flags.String("report", "", "also write the results to this .json or .csv file")
+	paretoOnly := flags.Bool("pareto-only", false, "only print the Pareto optimal configurations")
+	flags.Parse(args)
+	if err := flags.require("spec"); err != nil {
+		return err
+	}
+	if *reportPath != "" {
+		if ext := strings.ToLower(filepath.Ext(*reportPath)); ext != ".json" && ext != ".csv" {
+			return fmt.Errorf("sweep: -report must be a .json or .csv file, got %q", *reportPath)
+		}
+	}
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+
+	spec, err := sweep.LoadSpec(*specPath)
+	if err != nil {
+		return err
+	}
+	metric, err := evaluation.ParseMetric(spec.Metric)
+	if err != nil {
+		return err
+	}
+	configurations := spec.Configurations()
+	log.Printf("Sweeping %d configurations with %d ef_construction values each",
+		len(configurations), len(spec.EfConstruction))
+
+	queries, err := loadQueries(spec.Queries, spec.MaxQueries)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	truth, err := groundTruth(spec.Base, spec.GroundTruth, queries, int(spec.K), metric)
+	if err != nil {
+		return err
+	}
+	log.Printf("Computed the exact neighbors of %d queries in %v", len(queries), time.Since(start))
+
+	muopdbClient, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeClient(muopdbClient)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results, err := sweep.Run(ctx, muopdbClient, spec, queries, truth, func(result sweep.Result) {
+		if result.Error != "" {
+			log.Printf("%s (%s) failed: %s", result.Collection, sweep.FormatParameters(result.Parameters), result.Error)
+			return
+		}
+		log.Printf("%s ef=%d: recall %.4f, p50 %v, built in %v", result.Collection, result.EfConstruction,
+			result.Recall, result.Latency.P50, result.BuildTime.Round(time.Millisecond))
+	})
+	// An interrupted sweep still reports what it measured.
+	if len(results) > 0 {
+		fmt.Println()
+		if writeErr := sweep.WriteTable(os.Stdout, results, *paretoOnly); writeErr != nil {
+			return writeErr
+		}
+		if *reportPath != "" {
+			if writeErr := writeSweepReport(*reportPath, results); writeErr != nil {
+				return writeErr
+			}
+		}
+		printSweepCollections(results)
+	}
+	return err
+}
+
+func writeSweepReport(path string, results []sweep.Result) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = sweep.WriteJSON(file, results)
+	} else {
+		err = sweep.WriteCSV(file, results)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// printSweepCollections lists the collections left behind, MuopDB has no API
+// to drop them.
+func printSweepCollections(results []sweep.Result) {
+	var collections []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if !seen[result.Collection] {
+			seen[result.Collection] = true
+			collections = append(collections, result.Collection)
+		}
+	}
+	fmt.Printf("\nMuopDB cannot drop collections over its API, remove these from the server once done:\n  %s\n",
+		strings.Join(collections, "\n  "))
+}
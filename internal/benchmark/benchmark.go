@@ -0,0 +1,324 @@
+// Package benchmark drives a configurable load of searches and inserts against
+// a MuopDB collection and reports latencies, throughput and errors.
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"google.golang.org/grpc/status"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Operation string
+
+const (
+	OperationSearch Operation = "search"
+	OperationInsert Operation = "insert"
+)
+
+// defaultOpenLoopConcurrency bounds the requests in flight when a target QPS
+// is set without a concurrency.
+const defaultOpenLoopConcurrency = 64
+
+type Options struct {
+	CollectionName string
+	// The run stops after Duration or Requests, whichever comes first. At
+	// least one of them must be set.
+	Duration time.Duration
+	Requests int
+	// QPS, when set, schedules the requests at that rate with up to Concurrency
+	// of them in flight, and measures their latency from their scheduled time.
+	// Otherwise Concurrency workers send requests back to back.
+	QPS         float64
+	Concurrency int
+	// InsertRatio is the fraction of the requests that are inserts.
+	InsertRatio     float64
+	InsertBatchSize int
+	// InsertIDStart is the first doc id inserted, ids increase from there so
+	// the documents already in the collection are left alone.
+	InsertIDStart  uint64
+	TopK           uint32
+	EfConstruction uint32
+	RecordMetrics  bool
+	UserIds        []muopdbclient.DocID
+	// Seed makes the mix of operations reproducible.
+	Seed int64
+}
+
+// Workload is the data the requests are built from, both are cycled through.
+type Workload struct {
+	Queries [][]float32
+	Vectors [][]float32
+}
+
+// job is a request to send. scheduled is when a run with a target QPS meant
+// to send it, and is zero otherwise.
+type job struct {
+	op        Operation
+	scheduled time.Time
+}
+
+// recorder accumulates the results of one worker, so workers never contend.
+// Its memory does not grow with the number of requests.
+type recorder struct {
+	requests   int
+	errorCodes map[string]int
+	histogram  Histogram
+	// pages counts the searches by number of pages accessed.
+	pages map[uint64]int
+}
+
+func (r *recorder) merge(other *recorder) {
+	r.requests += other.requests
+	for code, count := range other.errorCodes {
+		r.errorCodes[code] += count
+	}
+	r.histogram.Merge(&other.histogram)
+	for pages, count := range other.pages {
+		r.pages[pages] += count
+	}
+}
+
+func newRecorders() map[Operation]*recorder {
+	return map[Operation]*recorder{
+		OperationSearch: {errorCodes: make(map[string]int), pages: make(map[uint64]int)},
+		OperationInsert: {errorCodes: make(map[string]int), pages: make(map[uint64]int)},
+	}
+}
+
+func (opts *Options) validate(workload Workload) error {
+	if opts.Duration <= 0 && opts.Requests <= 0 {
+		return errors.New("either a duration or a number of requests is required")
+	}
+	if opts.InsertRatio < 0 || opts.InsertRatio > 1 {
+		return fmt.Errorf("insert ratio must be between 0 and 1, got %v", opts.InsertRatio)
+	}
+	if opts.InsertRatio < 1 && len(workload.Queries) == 0 {
+		return errors.New("searches need queries")
+	}
+	if opts.InsertRatio > 0 && len(workload.Vectors) == 0 {
+		return errors.New("inserts need vectors")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+		if opts.QPS > 0 {
+			opts.Concurrency = defaultOpenLoopConcurrency
+		}
+	}
+	opts.InsertBatchSize = max(opts.InsertBatchSize, 1)
+	return nil
+}
+
+// Run sends the requests and reports on them. Requests in flight when the
+// duration elapses are completed and counted. When ctx is canceled, Run
+// returns the report of the requests completed so far along with ctx.Err().
+func Run(ctx context.Context, client muopdbclient.MuopDbClient, opts Options, workload Workload) (Report, error) {
+	if err := opts.validate(workload); err != nil {
+		return Report{}, err
+	}
+
+	dispatchCtx := ctx
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		dispatchCtx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var (
+		jobs       = make(chan job)
+		nextQuery  atomic.Uint64
+		nextInsert atomic.Uint64
+		wg         sync.WaitGroup
+		recorders  = make([]map[Operation]*recorder, opts.Concurrency)
+		start      = time.Now()
+	)
+
+	for w := range recorders {
+		recorders[w] = newRecorders()
+		wg.Add(1)
+		go func(recorders map[Operation]*recorder) {
+			defer wg.Done()
+			for job := range jobs {
+				var (
+					requestStart = time.Now()
+					pages        uint64
+					err          error
+				)
+				switch job.op {
+				case OperationSearch:
+					query := workload.Queries[(nextQuery.Add(1)-1)%uint64(len(workload.Queries))]
+					var response muopdbclient.SearchResponse
+					response, err = client.Search(ctx, muopdbclient.SearchRequest{
+						CollectionName: opts.CollectionName,
+						Vector:         query,
+						TopK:           opts.TopK,
+						EfConstruction: opts.EfConstruction,
+						RecordMetrics:  opts.RecordMetrics,
+						UserIds:        opts.UserIds,
+					})
+					pages = response.NumPagesAccessed
+				case OperationInsert:
+					first := nextInsert.Add(uint64(opts.InsertBatchSize)) - uint64(opts.InsertBatchSize)
+					request := muopdbclient.InsertRequest{
+						CollectionName: opts.CollectionName,
+						UserIds:        opts.UserIds,
+					}
+					for i := uint64(0); i < uint64(opts.InsertBatchSize); i++ {
+						request.DocIds = append(request.DocIds, muopdbclient.NewDocIDFromUint64(opts.InsertIDStart+first+i))
+						request.Vectors = append(request.Vectors,
+							workload.Vectors[(first+i)%uint64(len(workload.Vectors))]...)
+					}
+					_, err = client.Insert(ctx, request)
+				}
+				end := time.Now()
+
+				// Requests cut short by the caller giving up are not counted.
+				if err != nil && ctx.Err() != nil {
+					continue
+				}
+				r := recorders[job.op]
+				r.requests++
+				if err != nil {
+					r.errorCodes[status.Code(err).String()]++
+					continue
+				}
+				// Measuring from the scheduled time counts the time a request
+				// waited for a worker, which a slow server would otherwise hide
+				// by holding back the requests sent after it.
+				if job.scheduled.IsZero() {
+					r.histogram.Record(end.Sub(requestStart))
+				} else {
+					r.histogram.Record(end.Sub(job.scheduled))
+				}
+				if job.op == OperationSearch && opts.RecordMetrics {
+					r.pages[pages]++
+				}
+			}
+		}(recorders[w])
+	}
+
+	mix := rand.New(rand.NewSource(opts.Seed))
+dispatch:
+	for n := 0; opts.Requests <= 0 || n < opts.Requests; n++ {
+		next := job{op: OperationSearch}
+		if mix.Float64() < opts.InsertRatio {
+			next.op = OperationInsert
+		}
+		// The schedule is fixed up front, so requests held back by busy workers
+		// are sent back to back to catch up rather than pushed later.
+		if opts.QPS > 0 {
+			next.scheduled = start.Add(time.Duration(float64(n) / opts.QPS * float64(time.Second)))
+			if wait := time.Until(next.scheduled); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-dispatchCtx.Done():
+					break dispatch
+				}
+			}
+		}
+		select {
+		case jobs <- next:
+		case <-dispatchCtx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	total := newRecorders()
+	for _, workerRecorders := range recorders {
+		for op, r := range workerRecorders {
+			total[op].merge(r)
+		}
+	}
+	report := newReport(opts, start, elapsed, total)
+
+	// The caller giving up is an error, the duration running out is not.
+	if err := ctx.Err(); err != nil {
+		report.Interrupted = true
+		return report, err
+	}
+	return report, nil
+}
+
+func newReport(opts Options, start time.Time, elapsed time.Duration, recorders map[Operation]*recorder) Report {
+	report := Report{
+		Collection:      opts.CollectionName,
+		StartedAt:       start,
+		Elapsed:         elapsed,
+		TargetQPS:       opts.QPS,
+		Concurrency:     opts.Concurrency,
+		InsertRatio:     opts.InsertRatio,
+		InsertBatchSize: opts.InsertBatchSize,
+		TopK:            opts.TopK,
+		EfConstruction:  opts.EfConstruction,
+		RecordMetrics:   opts.RecordMetrics,
+	}
+
+	var requests int
+	for _, op := range []Operation{OperationSearch, OperationInsert} {
+		r := recorders[op]
+		if r.requests == 0 {
+			continue
+		}
+		requests += r.requests
+
+		errorCount := r.requests - r.histogram.Count()
+		stats := OperationReport{
+			Operation:  op,
+			Requests:   r.requests,
+			Errors:     errorCount,
+			ErrorRate:  float64(errorCount) / float64(r.requests),
+			Throughput: float64(r.histogram.Count()) / elapsed.Seconds(),
+			Latency:    r.histogram.Summary(),
+			Histogram:  r.histogram.Buckets(),
+		}
+		if errorCount > 0 {
+			stats.ErrorCodes = r.errorCodes
+		}
+		if len(r.pages) > 0 {
+			stats.PagesAccessed = summarizePages(r.pages)
+		}
+		report.Operations = append(report.Operations, stats)
+	}
+	report.AchievedQPS = float64(requests) / elapsed.Seconds()
+	return report
+}
+
+// summarizePages summarizes the searches counted by number of pages accessed.
+func summarizePages(pages map[uint64]int) *PagesSummary {
+	values := make([]uint64, 0, len(pages))
+	var total uint64
+	n := 0
+	for value, count := range pages {
+		values = append(values, value)
+		total += value * uint64(count)
+		n += count
+	}
+	slices.Sort(values)
+
+	// Nearest rank, as for the latencies.
+	at := func(p float64) uint64 {
+		rank := min(max(int(math.Ceil(p/100*float64(n))), 1), n)
+		for _, value := range values {
+			if rank -= pages[value]; rank <= 0 {
+				return value
+			}
+		}
+		return values[len(values)-1]
+	}
+	return &PagesSummary{
+		Mean: float64(total) / float64(n),
+		P50:  at(50),
+		P99:  at(99),
+		Max:  values[len(values)-1],
+	}
+}
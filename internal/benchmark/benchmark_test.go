@@ -0,0 +1,154 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	pb "github.com/TrungBui59/test_muopdb/api/pb"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"google.golang.org/grpc"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newClient(t *testing.T, opts ...grpc.ServerOption) (*muopdbtest.Server, muopdbclient.MuopDbClient) {
+	t.Helper()
+	server := muopdbtest.NewServer(opts...)
+	t.Cleanup(server.Close)
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := muopdbclient.NewClient(conn, muopdbclient.WithFlushPolicy(muopdbclient.FlushPolicy{}))
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	if err := client.CreateCollection(ctx, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Insert(ctx, muopdbclient.InsertRequest{
+		CollectionName: "docs",
+		DocIds:         []muopdbclient.DocID{muopdbclient.NewDocIDFromUint64(1)},
+		Vectors:        []float32{1, 2},
+		UserIds:        []muopdbclient.DocID{{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+// delay holds every search for d.
+func delay(d time.Duration) grpc.ServerOption {
+	return grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == pb.IndexServer_Search_FullMethodName {
+			time.Sleep(d)
+		}
+		return handler(ctx, req)
+	})
+}
+
+func options() Options {
+	return Options{
+		CollectionName: "docs",
+		Requests:       40,
+		Concurrency:    4,
+		TopK:           1,
+		UserIds:        []muopdbclient.DocID{{}},
+	}
+}
+
+func TestRunMixesOperations(t *testing.T) {
+	server, client := newClient(t)
+	opts := options()
+	opts.InsertRatio = 0.5
+	opts.InsertBatchSize = 3
+	opts.InsertIDStart = 100
+	report, err := Run(context.Background(), client, opts, Workload{
+		Queries: [][]float32{{1, 2}},
+		Vectors: [][]float32{{3, 4}, {5, 6}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := make(map[Operation]int)
+	for _, op := range report.Operations {
+		requests[op.Operation] = op.Requests
+		if op.Errors != 0 || op.Latency.Count != op.Requests {
+			t.Errorf("%s: %d errors, %d latencies for %d requests", op.Operation, op.Errors, op.Latency.Count, op.Requests)
+		}
+	}
+	if requests[OperationSearch]+requests[OperationInsert] != 40 || requests[OperationInsert] == 0 {
+		t.Errorf("sent %v, want 40 requests mixing searches and inserts", requests)
+	}
+	if n := server.IndexServer.NumDocuments("docs"); n != 1+3*requests[OperationInsert] {
+		t.Errorf("the server holds %d documents after %d inserts of 3", n, requests[OperationInsert])
+	}
+}
+
+func TestRunCountsErrors(t *testing.T) {
+	_, client := newClient(t)
+	opts := options()
+	opts.CollectionName = "missing"
+	report, err := Run(context.Background(), client, opts, Workload{Queries: [][]float32{{1, 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := report.Operations[0]
+	if op.Requests != 40 || op.Errors != 40 || op.ErrorRate != 1 || op.ErrorCodes["NotFound"] != 40 {
+		t.Errorf("report = %+v, want 40 NotFound errors", op)
+	}
+}
+
+func TestRunMeasuresFromTheSchedule(t *testing.T) {
+	_, client := newClient(t, delay(20*time.Millisecond))
+	opts := options()
+	opts.Requests = 5
+	opts.Concurrency = 1
+	opts.QPS = 1000
+	report, err := Run(context.Background(), client, opts, Workload{Queries: [][]float32{{1, 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fifth search was due after 4ms but waited for the four before it.
+	if latency := report.Operations[0].Latency; latency.Max < 80*time.Millisecond {
+		t.Errorf("latency = %+v, want the wait behind the earlier searches counted", latency)
+	}
+}
+
+func TestRunReportsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var searches atomic.Int32
+	_, client := newClient(t, grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == pb.IndexServer_Search_FullMethodName && searches.Add(1) == 3 {
+			cancel()
+		}
+		return handler(ctx, req)
+	}))
+
+	opts := options()
+	opts.Requests = 0
+	opts.Duration = time.Minute
+	opts.Concurrency = 1
+	report, err := Run(ctx, client, opts, Workload{Queries: [][]float32{{1, 2}}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run returned %v, want context.Canceled", err)
+	}
+	if !report.Interrupted || len(report.Operations) != 1 {
+		t.Fatalf("report = %+v, want the interrupted searches", report)
+	}
+	// The search that canceled is usually cut short, and then not counted.
+	if op := report.Operations[0]; op.Requests < 2 || op.Requests > 3 || op.Errors != 0 {
+		t.Errorf("report = %+v, want the searches completed before the cancel", op)
+	}
+}
+
+func TestSummarizePages(t *testing.T) {
+	summary := summarizePages(map[uint64]int{10: 98, 20: 1, 50: 1})
+	want := PagesSummary{Mean: 10.5, P50: 10, P99: 20, Max: 50}
+	if *summary != want {
+		t.Errorf("summarizePages = %+v, want %+v", *summary, want)
+	}
+}
@@ -0,0 +1,118 @@
+package benchmark
+
+import (
+	"github.com/TrungBui59/test_muopdb/internal/evaluation"
+	"math"
+	"time"
+)
+
+// Buckets grow by a quarter of a power of two from 1µs, which keeps every
+// bucket within 19% of its neighbors while covering up to hours.
+const (
+	histogramMin           = time.Microsecond
+	histogramStepsPerPower = 4
+	histogramBuckets       = 128
+)
+
+type HistogramBucket struct {
+	// UpperBound is exclusive, the last bucket also counts everything above.
+	UpperBound time.Duration `json:"upper_bound_ns"`
+	Count      int           `json:"count"`
+}
+
+// Histogram counts latencies in log-linear buckets. It keeps their exact
+// count, sum, min and max, and their percentiles to the bucket.
+type Histogram struct {
+	counts   [histogramBuckets]int
+	count    int
+	sum      time.Duration
+	min, max time.Duration
+}
+
+func bucketUpperBound(i int) time.Duration {
+	return time.Duration(float64(histogramMin) * math.Exp2(float64(i+1)/histogramStepsPerPower))
+}
+
+func (h *Histogram) Record(latency time.Duration) {
+	i := 0
+	if latency >= histogramMin {
+		i = int(math.Log2(float64(latency)/float64(histogramMin)) * histogramStepsPerPower)
+	}
+	h.counts[min(i, histogramBuckets-1)]++
+
+	if h.count == 0 || latency < h.min {
+		h.min = latency
+	}
+	h.max = max(h.max, latency)
+	h.count++
+	h.sum += latency
+}
+
+func (h *Histogram) Merge(other *Histogram) {
+	if other.count == 0 {
+		return
+	}
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	h.max = max(h.max, other.max)
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// Count returns the number of latencies recorded.
+func (h *Histogram) Count() int {
+	return h.count
+}
+
+// Percentile returns the upper bound of the bucket holding the nearest rank
+// percentile p, so at most 19% above the exact percentile, kept within the
+// min and max recorded. It returns zero when nothing was recorded.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := min(max(int(math.Ceil(p/100*float64(h.count))), 1), h.count)
+	for i, count := range h.counts {
+		if rank -= count; rank > 0 {
+			continue
+		}
+		if i == histogramBuckets-1 {
+			return h.max
+		}
+		return max(min(bucketUpperBound(i), h.max), h.min)
+	}
+	return h.max
+}
+
+// Summary describes the latencies recorded, see Percentile for the accuracy
+// of the percentiles.
+func (h *Histogram) Summary() evaluation.LatencySummary {
+	if h.count == 0 {
+		return evaluation.LatencySummary{}
+	}
+	return evaluation.LatencySummary{
+		Count: h.count,
+		Mean:  h.sum / time.Duration(h.count),
+		Min:   h.min,
+		P50:   h.Percentile(50),
+		P90:   h.Percentile(90),
+		P99:   h.Percentile(99),
+		P999:  h.Percentile(99.9),
+		Max:   h.max,
+	}
+}
+
+// Buckets returns the non-empty buckets in increasing order.
+func (h *Histogram) Buckets() []HistogramBucket {
+	var buckets []HistogramBucket
+	for i, count := range h.counts {
+		if count > 0 {
+			buckets = append(buckets, HistogramBucket{UpperBound: bucketUpperBound(i), Count: count})
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,84 @@
+package benchmark
+
+import (
+	"github.com/TrungBui59/test_muopdb/internal/evaluation"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	var h Histogram
+	for _, latency := range []time.Duration{0, time.Microsecond, time.Millisecond, time.Millisecond, 1000 * time.Hour} {
+		h.Record(latency)
+	}
+	buckets := h.Buckets()
+	if len(buckets) != 3 {
+		t.Fatalf("buckets = %+v, want 3 of them", buckets)
+	}
+	if buckets[0].Count != 2 || buckets[0].UpperBound != bucketUpperBound(0) {
+		t.Errorf("the first bucket %+v should hold the latencies up to 1µs", buckets[0])
+	}
+	if buckets[1].Count != 2 || buckets[1].UpperBound <= time.Millisecond || buckets[1].UpperBound > 1190*time.Microsecond {
+		t.Errorf("the bucket of 1ms is %+v", buckets[1])
+	}
+	if last := buckets[2]; last.UpperBound != bucketUpperBound(histogramBuckets-1) || last.Count != 1 {
+		t.Errorf("the last bucket %+v should count the latencies above its bound", last)
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i].UpperBound <= buckets[i-1].UpperBound {
+			t.Errorf("bucket %d does not follow bucket %d: %+v", i, i-1, buckets)
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	var h Histogram
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	for _, p := range []float64{1, 50, 90, 99, 99.9} {
+		exact := time.Duration(p*10) * time.Millisecond
+		got := h.Percentile(p)
+		if got < exact || float64(got) > 1.19*float64(exact) {
+			t.Errorf("Percentile(%v) = %v, want within 19%% above %v", p, got, exact)
+		}
+	}
+	if got := h.Percentile(100); got != time.Second {
+		t.Errorf("Percentile(100) = %v, want the max 1s", got)
+	}
+
+	summary := h.Summary()
+	if summary.Count != 1000 || summary.Min != time.Millisecond || summary.Max != time.Second || summary.Mean != 500500*time.Microsecond {
+		t.Errorf("Summary() = %+v, want the exact count, min, max and mean", summary)
+	}
+
+	var single Histogram
+	single.Record(3 * time.Millisecond)
+	if got := single.Percentile(50); got != 3*time.Millisecond {
+		t.Errorf("the median of a single latency is %v, want it clamped to 3ms", got)
+	}
+	var empty Histogram
+	if empty.Percentile(50) != 0 || empty.Summary() != (evaluation.LatencySummary{}) {
+		t.Error("an empty histogram should summarize to zero")
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	var a, b, empty Histogram
+	a.Record(2 * time.Millisecond)
+	b.Record(time.Millisecond)
+	b.Record(5 * time.Millisecond)
+	a.Merge(&b)
+	a.Merge(&empty)
+	empty.Merge(&a)
+
+	for _, h := range []*Histogram{&a, &empty} {
+		summary := h.Summary()
+		if summary.Count != 3 || summary.Min != time.Millisecond || summary.Max != 5*time.Millisecond {
+			t.Errorf("merged summary = %+v, want 3 latencies from 1ms to 5ms", summary)
+		}
+		if got := h.Buckets(); len(got) != 3 {
+			t.Errorf("merged buckets = %+v, want 3", got)
+		}
+	}
+}
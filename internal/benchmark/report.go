@@ -0,0 +1,101 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/TrungBui59/test_muopdb/internal/evaluation"
+	"io"
+	"strconv"
+	"time"
+)
+
+type PagesSummary struct {
+	Mean float64 `json:"mean"`
+	P50  uint64  `json:"p50"`
+	P99  uint64  `json:"p99"`
+	Max  uint64  `json:"max"`
+}
+
+// OperationReport describes the requests of one kind. Latencies only cover the
+// requests that succeeded, and their percentiles are histogram bucket bounds.
+type OperationReport struct {
+	Operation  Operation      `json:"operation"`
+	Requests   int            `json:"requests"`
+	Errors     int            `json:"errors"`
+	ErrorRate  float64        `json:"error_rate"`
+	ErrorCodes map[string]int `json:"error_codes,omitempty"`
+	// Throughput counts the successful requests per second.
+	Throughput float64                   `json:"throughput"`
+	Latency    evaluation.LatencySummary `json:"latency"`
+	Histogram  []HistogramBucket         `json:"histogram"`
+	// PagesAccessed is only set when the searches recorded metrics.
+	PagesAccessed *PagesSummary `json:"pages_accessed,omitempty"`
+}
+
+// Report holds the results of a run along with the settings it ran with, so
+// runs against different servers or collections can be compared.
+type Report struct {
+	// Label is free text identifying the run, such as the server version.
+	Label           string            `json:"label,omitempty"`
+	Collection      string            `json:"collection"`
+	StartedAt       time.Time         `json:"started_at"`
+	Elapsed         time.Duration     `json:"elapsed_ns"`
+	TargetQPS       float64           `json:"target_qps,omitempty"`
+	AchievedQPS     float64           `json:"achieved_qps"`
+	Concurrency     int               `json:"concurrency"`
+	InsertRatio     float64           `json:"insert_ratio"`
+	InsertBatchSize int               `json:"insert_batch_size"`
+	TopK            uint32            `json:"top_k"`
+	EfConstruction  uint32            `json:"ef_construction"`
+	RecordMetrics   bool              `json:"record_metrics"`
+	Operations      []OperationReport `json:"operations"`
+	// Interrupted is set when the run was canceled before its end, the report
+	// then covers the requests completed until then.
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+var csvHeader = []string{
+	"label", "collection", "started_at", "elapsed_s", "target_qps", "achieved_qps", "concurrency",
+	"insert_ratio", "top_k", "ef_construction", "operation", "requests", "errors", "error_rate",
+	"throughput", "mean_us", "p50_us", "p90_us", "p99_us", "p999_us", "max_us", "pages_mean", "pages_p99",
+}
+
+// WriteCSV writes one row per operation. The header is optional so the rows of
+// successive runs can be appended to the same file.
+func (r Report) WriteCSV(w io.Writer, header bool) error {
+	writer := csv.NewWriter(w)
+	if header {
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	float := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	micros := func(d time.Duration) string { return float(float64(d) / float64(time.Microsecond)) }
+	for _, op := range r.Operations {
+		pagesMean, pagesP99 := "", ""
+		if op.PagesAccessed != nil {
+			pagesMean = float(op.PagesAccessed.Mean)
+			pagesP99 = strconv.FormatUint(op.PagesAccessed.P99, 10)
+		}
+		err := writer.Write([]string{
+			r.Label, r.Collection, r.StartedAt.Format(time.RFC3339), float(r.Elapsed.Seconds()),
+			float(r.TargetQPS), float(r.AchievedQPS), strconv.Itoa(r.Concurrency),
+			float(r.InsertRatio), strconv.FormatUint(uint64(r.TopK), 10), strconv.FormatUint(uint64(r.EfConstruction), 10),
+			string(op.Operation), strconv.Itoa(op.Requests), strconv.Itoa(op.Errors), float(op.ErrorRate),
+			float(op.Throughput), micros(op.Latency.Mean), micros(op.Latency.P50), micros(op.Latency.P90),
+			micros(op.Latency.P99), micros(op.Latency.P999), micros(op.Latency.Max), pagesMean, pagesP99,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,33 +1,47 @@
 package configs
 
 import (
+	"fmt"
 	"github.com/TrungBui59/test_muopdb/config"
 	"gopkg.in/yaml.v2"
 	"os"
 )
 
 type Config struct {
-	MuopDBConfig MuopDBConfig `yaml:"muopdb"`
-	HttpConfig   HttpConfig   `yaml:"http"`
-	GeminiConfig GeminiConfig `yaml:"gemini"`
+	MuopDBConfig    MuopDBConfig    `yaml:"muopdb"`
+	HttpConfig      HttpConfig      `yaml:"http"`
+	GeminiConfig    GeminiConfig    `yaml:"gemini"`
+	EmbeddingConfig EmbeddingConfig `yaml:"embedding"`
+	DocStoreConfig  DocStoreConfig  `yaml:"docstore"`
 }
 
-func NewConfig(configPath string) (Config, error) {
-	var (
-		cfg         = Config{}
-		configBytes = config.DefaultConfig
-		err         error
-	)
+// NewConfig builds the effective config from layers, each overriding the
+// previous one: the embedded defaults, the file at configPath when set, the
+// environment variables named after the yaml keys with a MUOPDB_ prefix
+// (MUOPDB_HTTP_PORT for http.port, MUOPDB_HOST for muopdb.host, and
+// GEMINI_API_KEY for gemini.api_key), and finally overrides, keyed by dotted
+// yaml key.
+func NewConfig(configPath string, overrides map[string]string) (Config, error) {
+	cfg := Config{}
+	if err := yaml.Unmarshal(config.DefaultConfig, &cfg); err != nil {
+		return Config{}, err
+	}
 
 	if configPath != "" {
-		configBytes, err = os.ReadFile(configPath)
+		configBytes, err := os.ReadFile(configPath)
 		if err != nil {
 			return Config{}, err
 		}
+		// Unmarshaling over the defaults keeps the keys the file leaves out.
+		if err := yaml.Unmarshal(configBytes, &cfg); err != nil {
+			return Config{}, fmt.Errorf("parsing %s: %w", configPath, err)
+		}
 	}
 
-	err = yaml.Unmarshal(configBytes, &cfg)
-	if err != nil {
+	if err := cfg.applyEnv(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.applyOverrides(overrides); err != nil {
 		return Config{}, err
 	}
 	return cfg, nil
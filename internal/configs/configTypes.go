@@ -1,15 +1,65 @@
 package configs
 
 type MuopDBConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host  string      `yaml:"host"`
+	Port  int         `yaml:"port"`
+	Retry RetryConfig `yaml:"retry"`
+}
+
+type RetryConfig struct {
+	MaxAttempts       int     `yaml:"max_attempts"`
+	InitialBackoffMs  int     `yaml:"initial_backoff_ms"`
+	MaxBackoffMs      int     `yaml:"max_backoff_ms"`
+	BackoffMultiplier float64 `yaml:"backoff_multiplier"`
+	Jitter            float64 `yaml:"jitter"`
+	BudgetMaxTokens   float64 `yaml:"budget_max_tokens"`
+	BudgetTokenRatio  float64 `yaml:"budget_token_ratio"`
+	// RetryInserts retries failed inserts too. Only safe when every insert
+	// carries its own doc ids.
+	RetryInserts bool `yaml:"retry_inserts"`
 }
 
 type HttpConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host                   string `yaml:"host"`
+	Port                   int    `yaml:"port"`
+	ReadTimeoutSeconds     int    `yaml:"read_timeout_seconds"`
+	WriteTimeoutSeconds    int    `yaml:"write_timeout_seconds"`
+	IdleTimeoutSeconds     int    `yaml:"idle_timeout_seconds"`
+	ShutdownTimeoutSeconds int    `yaml:"shutdown_timeout_seconds"`
 }
 
 type GeminiConfig struct {
-	APIKey string `yaml:"api_key"`
+	APIKey string `yaml:"api_key" secret:"true" env:"GEMINI_API_KEY"`
+}
+
+type EmbeddingConfig struct {
+	// Provider is one of "gemini", "openai" or "hashing".
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`
+	// Dimension is learned from the provider when zero.
+	Dimension int `yaml:"dimension"`
+	// BatchSize is the number of texts per embedding request, the provider
+	// limit when zero.
+	BatchSize         int          `yaml:"batch_size"`
+	Concurrency       int          `yaml:"concurrency"`
+	RequestsPerSecond float64      `yaml:"requests_per_second"`
+	OpenAIConfig      OpenAIConfig `yaml:"openai"`
+	CacheConfig       CacheConfig  `yaml:"cache"`
+}
+
+type CacheConfig struct {
+	// Dir disables the cache when empty.
+	Dir        string `yaml:"dir"`
+	MaxBytes   int64  `yaml:"max_bytes"`
+	MaxEntries int    `yaml:"max_entries"`
+}
+
+type OpenAIConfig struct {
+	BaseURL string `yaml:"base_url"`
+	APIKey  string `yaml:"api_key" secret:"true" env:"OPENAI_API_KEY"`
+}
+
+type DocStoreConfig struct {
+	// Path of the document store file, documents are not recorded when empty.
+	Path string `yaml:"path"`
 }
@@ -0,0 +1,165 @@
+package configs
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+// envPrefix starts every environment variable read, so variables set for other
+// programs, like HTTP_PORT, are never mistaken for settings.
+const envPrefix = "MUOPDB_"
+
+// setting is a leaf of the config, addressed by the yaml keys leading to it.
+type setting struct {
+	path   []string
+	secret bool
+	// envAlias, from the `env` tag, is a conventional variable read when the
+	// prefixed one is unset, such as GEMINI_API_KEY.
+	envAlias string
+	value    reflect.Value
+}
+
+// Key is the dotted yaml path of the setting, as accepted by overrides.
+func (s setting) Key() string {
+	return strings.Join(s.path, ".")
+}
+
+// EnvVar is the environment variable overriding the setting, e.g.
+// MUOPDB_HTTP_PORT for http.port. The muopdb section is not repeated after the
+// prefix, so muopdb.host is read from MUOPDB_HOST.
+func (s setting) EnvVar() string {
+	path := s.path
+	if len(path) > 1 && path[0] == "muopdb" {
+		path = path[1:]
+	}
+	return envPrefix + strings.ToUpper(strings.Join(path, "_"))
+}
+
+// EnvVars lists the environment variables read for the setting, in order of
+// precedence.
+func (s setting) EnvVars() []string {
+	if s.envAlias == "" {
+		return []string{s.EnvVar()}
+	}
+	return []string{s.EnvVar(), s.envAlias}
+}
+
+func (s setting) set(raw string) error {
+	if s.value.Kind() == reflect.String {
+		s.value.SetString(raw)
+		return nil
+	}
+	// Parse the same way the config file is, so "1e9" or "0x10" mean the same
+	// in both places.
+	parsed := reflect.New(s.value.Type())
+	if err := yaml.Unmarshal([]byte(raw), parsed.Interface()); err != nil {
+		return fmt.Errorf("invalid value %q for %s: %w", raw, s.Key(), err)
+	}
+	s.value.Set(parsed.Elem())
+	return nil
+}
+
+// settings lists the leaves of the struct v points to. Fields tagged
+// `secret:"true"` are redacted when the config is printed, fields tagged
+// `env:"NAME"` are also read from the NAME environment variable.
+func settings(v reflect.Value, path []string) []setting {
+	var leaves []setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		fieldPath := append(append([]string{}, path...), key)
+		if field.Type.Kind() == reflect.Struct {
+			leaves = append(leaves, settings(v.Field(i), fieldPath)...)
+			continue
+		}
+		leaves = append(leaves, setting{
+			path:     fieldPath,
+			secret:   field.Tag.Get("secret") == "true",
+			envAlias: field.Tag.Get("env"),
+			value:    v.Field(i),
+		})
+	}
+	return leaves
+}
+
+// EnvVars maps the dotted key of every setting to its environment variables,
+// in order of precedence.
+func EnvVars() map[string][]string {
+	var cfg Config
+	keys := make(map[string][]string)
+	for _, s := range settings(reflect.ValueOf(&cfg).Elem(), nil) {
+		keys[s.Key()] = s.EnvVars()
+	}
+	return keys
+}
+
+func (cfg *Config) applyEnv() error {
+	for _, s := range settings(reflect.ValueOf(cfg).Elem(), nil) {
+		for _, name := range s.EnvVars() {
+			raw, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := s.set(raw); err != nil {
+				return fmt.Errorf("environment variable %s: %w", name, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) applyOverrides(overrides map[string]string) error {
+	byKey := make(map[string]setting)
+	for _, s := range settings(reflect.ValueOf(cfg).Elem(), nil) {
+		byKey[s.Key()] = s
+	}
+
+	// Sorted so the first error reported does not depend on map order.
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("unknown config key %q", key)
+		}
+		if err := s.set(overrides[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with its secrets masked, safe to print
+// or log.
+func (cfg Config) Redacted() Config {
+	for _, s := range settings(reflect.ValueOf(&cfg).Elem(), nil) {
+		if s.secret && s.value.String() != "" {
+			s.value.SetString(redacted)
+		}
+	}
+	return cfg
+}
+
+// String formats the config as YAML with its secrets masked.
+func (cfg Config) String() string {
+	out, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return fmt.Sprintf("invalid config: %v", err)
+	}
+	return string(out)
+}
@@ -0,0 +1,78 @@
+package configs
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEnvVarsArePrefixed(t *testing.T) {
+	envVars := EnvVars()
+	tests := map[string][]string{
+		"http.port":                   {"MUOPDB_HTTP_PORT"},
+		"muopdb.host":                 {"MUOPDB_HOST"},
+		"gemini.api_key":              {"MUOPDB_GEMINI_API_KEY", "GEMINI_API_KEY"},
+		"embedding.openai.api_key":    {"MUOPDB_EMBEDDING_OPENAI_API_KEY", "OPENAI_API_KEY"},
+		"embedding.cache.max_entries": {"MUOPDB_EMBEDDING_CACHE_MAX_ENTRIES"},
+	}
+	for key, want := range tests {
+		if got := envVars[key]; !slices.Equal(got, want) {
+			t.Errorf("%s is read from %v, want %v", key, got, want)
+		}
+	}
+	for key, names := range envVars {
+		if !strings.HasPrefix(names[0], envPrefix) {
+			t.Errorf("%s is read from %s, which lacks the %s prefix", key, names[0], envPrefix)
+		}
+	}
+}
+
+func TestNewConfigLayers(t *testing.T) {
+	t.Setenv("HTTP_PORT", "1")
+	t.Setenv("MUOPDB_HTTP_PORT", "9090")
+	t.Setenv("MUOPDB_HOST", "from-env")
+	t.Setenv("MUOPDB_PORT", "9003")
+	t.Setenv("GEMINI_API_KEY", "secret-key")
+
+	cfg, err := NewConfig("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HttpConfig.Port != 9090 {
+		t.Errorf("http.port = %d, want 9090 from MUOPDB_HTTP_PORT", cfg.HttpConfig.Port)
+	}
+	if cfg.MuopDBConfig.Host != "from-env" || cfg.MuopDBConfig.Port != 9003 {
+		t.Errorf("muopdb = %s:%d, want from-env:9003 from MUOPDB_HOST and MUOPDB_PORT", cfg.MuopDBConfig.Host, cfg.MuopDBConfig.Port)
+	}
+	if cfg.GeminiConfig.APIKey != "secret-key" || strings.Contains(cfg.String(), "secret-key") {
+		t.Errorf("gemini.api_key = %q and printed as %q, want it set from GEMINI_API_KEY and redacted", cfg.GeminiConfig.APIKey, cfg.String())
+	}
+
+	cfg, err = NewConfig("", map[string]string{"muopdb.host": "from-flag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MuopDBConfig.Host != "from-flag" {
+		t.Errorf("muopdb.host = %q, want the override to win over the environment", cfg.MuopDBConfig.Host)
+	}
+
+	if _, err := NewConfig("", map[string]string{"muopdb.hots": "x"}); err == nil {
+		t.Error("an unknown override key was accepted")
+	}
+	if _, err := NewConfig("", map[string]string{"http.port": "eighty"}); err == nil {
+		t.Error("an invalid override value was accepted")
+	}
+}
+
+func TestPrefixedEnvVarWinsOverAlias(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "shared-key")
+	t.Setenv("MUOPDB_GEMINI_API_KEY", "muopdb-key")
+
+	cfg, err := NewConfig("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GeminiConfig.APIKey != "muopdb-key" {
+		t.Errorf("gemini.api_key = %q, want MUOPDB_GEMINI_API_KEY to win over GEMINI_API_KEY", cfg.GeminiConfig.APIKey)
+	}
+}
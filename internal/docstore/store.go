@@ -0,0 +1,96 @@
+// Package docstore keeps the source text and metadata of indexed documents, so
+// search results can be mapped back to what was ingested.
+package docstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+type Document struct {
+	ID             muopdbclient.DocID `json:"id"`
+	Text           string             `json:"text"`
+	Source         string             `json:"source,omitempty"`
+	Metadata       map[string]string  `json:"metadata,omitempty"`
+	EmbeddingModel string             `json:"embedding_model,omitempty"`
+}
+
+// Store is a bbolt file with one bucket per collection, keyed by the 16 bytes
+// of the doc id.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the store at path. It fails if another process holds
+// the file.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening document store %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put records the documents of a collection, replacing those with the same id.
+func (s *Store) Put(collectionName string, docs ...Document) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(collectionName))
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			value, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(doc.ID.Bytes(), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the document with the given id, if known.
+func (s *Store) Get(collectionName string, id muopdbclient.DocID) (Document, bool, error) {
+	docs, err := s.GetMany(collectionName, []muopdbclient.DocID{id})
+	if err != nil {
+		return Document{}, false, err
+	}
+	doc, ok := docs[id]
+	return doc, ok, nil
+}
+
+// GetMany returns the known documents among ids.
+func (s *Store) GetMany(collectionName string, ids []muopdbclient.DocID) (map[muopdbclient.DocID]Document, error) {
+	docs := make(map[muopdbclient.DocID]Document, len(ids))
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collectionName))
+		if bucket == nil {
+			return nil
+		}
+		for _, id := range ids {
+			value := bucket.Get(id.Bytes())
+			if value == nil {
+				continue
+			}
+			var doc Document
+			if err := json.Unmarshal(value, &doc); err != nil {
+				return fmt.Errorf("decoding document %s: %w", id, err)
+			}
+			docs[id] = doc
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
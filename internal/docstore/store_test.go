@@ -0,0 +1,113 @@
+package docstore
+
+import (
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openStore(t *testing.T, path string) *Store {
+	t.Helper()
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+var (
+	first = Document{
+		ID:             muopdbclient.NewDocIDFromUint64(1),
+		Text:           "the first document",
+		Source:         "docs.txt",
+		Metadata:       map[string]string{"lang": "en"},
+		EmbeddingModel: "hashing-8",
+	}
+	second = Document{ID: muopdbclient.DocID{Low: 2, High: 7}, Text: "the second document"}
+)
+
+func TestPutGetRoundTrip(t *testing.T) {
+	store := openStore(t, filepath.Join(t.TempDir(), "documents.db"))
+	defer store.Close()
+
+	if err := store.Put("docs", first, second); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []Document{first, second} {
+		got, ok, err := store.Get("docs", want.ID)
+		if err != nil || !ok {
+			t.Fatalf("Get(%s) = %v, %v", want.ID, ok, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Get(%s) = %+v, want %+v", want.ID, got, want)
+		}
+	}
+
+	replaced := Document{ID: first.ID, Text: "replaced"}
+	if err := store.Put("docs", replaced); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := store.Get("docs", first.ID); !reflect.DeepEqual(got, replaced) {
+		t.Errorf("Get after replacing = %+v, want %+v", got, replaced)
+	}
+	// Collections do not share documents.
+	if _, ok, err := store.Get("other", second.ID); ok || err != nil {
+		t.Errorf("Get from another collection = %v, %v, want nothing", ok, err)
+	}
+}
+
+func TestGetManySkipsMissingIDs(t *testing.T) {
+	store := openStore(t, filepath.Join(t.TempDir(), "documents.db"))
+	defer store.Close()
+	if err := store.Put("docs", first, second); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := muopdbclient.NewDocIDFromUint64(3)
+	docs, err := store.GetMany("docs", []muopdbclient.DocID{missing, second.ID, first.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[muopdbclient.DocID]Document{first.ID: first, second.ID: second}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("GetMany = %+v, want %+v", docs, want)
+	}
+}
+
+func TestReadsBeforeAnyPut(t *testing.T) {
+	store := openStore(t, filepath.Join(t.TempDir(), "documents.db"))
+	defer store.Close()
+
+	docs, err := store.GetMany("docs", []muopdbclient.DocID{first.ID})
+	if err != nil || len(docs) != 0 {
+		t.Errorf("GetMany on a missing collection = %v, %v, want no documents", docs, err)
+	}
+	if _, ok, err := store.Get("docs", first.ID); ok || err != nil {
+		t.Errorf("Get on a missing collection = %v, %v, want nothing", ok, err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "documents.db")
+	store := openStore(t, path)
+	if err := store.Put("docs", first); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is locked while open.
+	if locked, err := Open(path); err == nil {
+		locked.Close()
+		t.Error("opened the store twice")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openStore(t, path)
+	defer reopened.Close()
+	got, ok, err := reopened.Get("docs", first.ID)
+	if err != nil || !ok || !reflect.DeepEqual(got, first) {
+		t.Errorf("Get after reopening = %+v, %v, %v, want %+v", got, ok, err, first)
+	}
+}
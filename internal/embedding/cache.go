@@ -0,0 +1,295 @@
+package embedding
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type CacheOptions struct {
+	// Dir holds one file per cached embedding.
+	Dir string
+	// MaxBytes and MaxEntries bound the cache, least recently used entries are
+	// evicted first. Zero means unlimited.
+	MaxBytes   int64
+	MaxEntries int
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// CachedEmbedder keeps the embeddings of an Embedder on disk, keyed by provider,
+// model name and a hash of the text, so embedding the same text again is free.
+type CachedEmbedder struct {
+	Embedder
+	provider string
+	opts     CacheOptions
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	stats   CacheStats
+}
+
+// NewCachedEmbedder loads the index of the entries already in opts.Dir.
+func NewCachedEmbedder(embedder Embedder, provider string, opts CacheOptions) (*CachedEmbedder, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	c := &CachedEmbedder{
+		Embedder: embedder,
+		provider: provider,
+		opts:     opts,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	if err := c.loadIndex(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c, nil
+}
+
+func (c *CachedEmbedder) loadIndex() error {
+	type file struct {
+		entry   cacheEntry
+		modTime time.Time
+	}
+	var files []file
+
+	err := filepath.WalkDir(c.opts.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".bin" {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		key := filepath.Base(path)
+		files = append(files, file{
+			entry:   cacheEntry{key: key[:len(key)-len(".bin")], size: info.Size()},
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Most recently used first, the modification time is refreshed on hits.
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	for _, f := range files {
+		c.entries[f.entry.key] = c.lru.PushBack(f.entry)
+		c.stats.Bytes += f.entry.size
+	}
+	c.stats.Entries = len(c.entries)
+	return nil
+}
+
+func (c *CachedEmbedder) key(text string) string {
+	h := sha256.New()
+	io.WriteString(h, c.provider)
+	h.Write([]byte{0})
+	io.WriteString(h, c.Embedder.ModelName())
+	h.Write([]byte{0})
+	io.WriteString(h, text)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *CachedEmbedder) path(key string) string {
+	return filepath.Join(c.opts.Dir, key[:2], key+".bin")
+}
+
+func (c *CachedEmbedder) get(key string) ([]float32, bool) {
+	c.mu.Lock()
+	element, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(element)
+	}
+	c.mu.Unlock()
+
+	if ok {
+		data, err := os.ReadFile(c.path(key))
+		if err == nil && len(data)%4 == 0 && len(data) > 0 {
+			now := time.Now()
+			_ = os.Chtimes(c.path(key), now, now)
+
+			vector := make([]float32, len(data)/4)
+			for i := range vector {
+				vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+			}
+			c.mu.Lock()
+			c.stats.Hits++
+			c.mu.Unlock()
+			return vector, true
+		}
+		c.mu.Lock()
+		c.remove(key)
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	c.stats.Misses++
+	c.mu.Unlock()
+	return nil, false
+}
+
+func (c *CachedEmbedder) put(key string, vector []float32) error {
+	data := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.stats.Bytes -= element.Value.(cacheEntry).size
+		c.lru.Remove(element)
+	}
+	c.entries[key] = c.lru.PushFront(cacheEntry{key: key, size: int64(len(data))})
+	c.stats.Bytes += int64(len(data))
+	c.stats.Entries = len(c.entries)
+	c.evict()
+	return nil
+}
+
+// remove drops an entry. Callers hold c.mu.
+func (c *CachedEmbedder) remove(key string) {
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(element)
+	delete(c.entries, key)
+	c.stats.Bytes -= element.Value.(cacheEntry).size
+	c.stats.Entries = len(c.entries)
+	_ = os.Remove(c.path(key))
+}
+
+// evict removes least recently used entries until the cache fits its limits.
+// Callers hold c.mu.
+func (c *CachedEmbedder) evict() {
+	for c.lru.Len() > 0 &&
+		((c.opts.MaxBytes > 0 && c.stats.Bytes > c.opts.MaxBytes) ||
+			(c.opts.MaxEntries > 0 && len(c.entries) > c.opts.MaxEntries)) {
+		c.remove(c.lru.Back().Value.(cacheEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+func (c *CachedEmbedder) EmbedOne(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (c *CachedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+
+	var (
+		missTexts   []string
+		missIndexes []int
+	)
+	for i, text := range texts {
+		keys[i] = c.key(text)
+		if vector, ok := c.get(keys[i]); ok {
+			vectors[i] = vector
+			continue
+		}
+		missTexts = append(missTexts, text)
+		missIndexes = append(missIndexes, i)
+	}
+	if len(missTexts) == 0 {
+		return vectors, nil
+	}
+
+	missVectors, err := c.Embedder.EmbedBatch(ctx, missTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(missVectors) != len(missTexts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(missVectors), len(missTexts))
+	}
+
+	for i, vector := range missVectors {
+		vectors[missIndexes[i]] = vector
+		// Failing to cache does not fail the embedding, the text is simply
+		// embedded again next time.
+		if err := c.put(keys[missIndexes[i]], vector); err != nil {
+			log.Printf("Error caching embedding: %v", err)
+		}
+	}
+	return vectors, nil
+}
+
+// MaxBatchSize forwards the limit of the wrapped embedder, if any.
+func (c *CachedEmbedder) MaxBatchSize() int {
+	if limiter, ok := c.Embedder.(BatchLimiter); ok {
+		return limiter.MaxBatchSize()
+	}
+	return 0
+}
+
+// Close closes the wrapped embedder if it holds resources.
+func (c *CachedEmbedder) Close() error {
+	if closer, ok := c.Embedder.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (c *CachedEmbedder) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
@@ -0,0 +1,169 @@
+package embedding
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingEmbedder embeds like a HashingEmbedder of 4 features, whose vectors
+// take 16 bytes on disk, and records the texts it was asked for. extra is
+// added to the number of vectors it returns.
+type countingEmbedder struct {
+	*HashingEmbedder
+	extra    int
+	embedded []string
+}
+
+func (e *countingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.embedded = append(e.embedded, texts...)
+	vectors, err := e.HashingEmbedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if e.extra >= 0 {
+		return append(vectors, make([][]float32, e.extra)...), nil
+	}
+	return vectors[:len(vectors)+e.extra], nil
+}
+
+func newCache(t *testing.T, dir string, opts CacheOptions) (*CachedEmbedder, *countingEmbedder) {
+	t.Helper()
+	inner := &countingEmbedder{HashingEmbedder: NewHashingEmbedder(4)}
+	opts.Dir = dir
+	cache, err := NewCachedEmbedder(inner, ProviderHashing, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, inner
+}
+
+func embed(t *testing.T, cache *CachedEmbedder, texts ...string) {
+	t.Helper()
+	vectors, err := cache.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range texts {
+		want, _ := NewHashingEmbedder(4).EmbedOne(context.Background(), text)
+		if !reflect.DeepEqual(vectors[i], want) {
+			t.Fatalf("the vector of %q is %v, want %v", text, vectors[i], want)
+		}
+	}
+}
+
+func TestCacheHitsAndMisses(t *testing.T) {
+	cache, inner := newCache(t, t.TempDir(), CacheOptions{})
+	embed(t, cache, "alpha", "beta")
+	embed(t, cache, "alpha", "gamma", "beta")
+
+	if want := []string{"alpha", "beta", "gamma"}; !reflect.DeepEqual(inner.embedded, want) {
+		t.Errorf("embedded %v, want every text once: %v", inner.embedded, want)
+	}
+	want := CacheStats{Hits: 2, Misses: 3, Entries: 3, Bytes: 48}
+	if stats := cache.Stats(); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestCacheEvictsByMaxEntries(t *testing.T) {
+	cache, inner := newCache(t, t.TempDir(), CacheOptions{MaxEntries: 2})
+	embed(t, cache, "alpha")
+	embed(t, cache, "beta")
+	// Using alpha again makes beta the least recently used.
+	embed(t, cache, "alpha")
+	embed(t, cache, "gamma")
+
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Evictions != 1 || stats.Bytes != 32 {
+		t.Errorf("stats = %+v, want 2 entries left after 1 eviction", stats)
+	}
+	inner.embedded = nil
+	embed(t, cache, "alpha", "gamma", "beta")
+	if want := []string{"beta"}; !reflect.DeepEqual(inner.embedded, want) {
+		t.Errorf("embedded %v again, want only the evicted %v", inner.embedded, want)
+	}
+}
+
+func TestCacheEvictsByMaxBytes(t *testing.T) {
+	cache, inner := newCache(t, t.TempDir(), CacheOptions{MaxBytes: 40})
+	embed(t, cache, "alpha", "beta", "gamma")
+
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Bytes != 32 || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want 2 entries of 16 bytes under the 40 bytes limit", stats)
+	}
+	inner.embedded = nil
+	embed(t, cache, "beta", "gamma", "alpha")
+	if want := []string{"alpha"}; !reflect.DeepEqual(inner.embedded, want) {
+		t.Errorf("embedded %v again, want only the evicted %v", inner.embedded, want)
+	}
+}
+
+func TestCacheReloadsIndex(t *testing.T) {
+	dir := t.TempDir()
+	cache, _ := newCache(t, dir, CacheOptions{})
+	embed(t, cache, "alpha", "beta")
+
+	reloaded, inner := newCache(t, dir, CacheOptions{})
+	if stats := reloaded.Stats(); stats.Entries != 2 || stats.Bytes != 32 {
+		t.Errorf("reloaded stats = %+v, want the 2 entries on disk", stats)
+	}
+	embed(t, reloaded, "beta", "alpha")
+	if len(inner.embedded) != 0 || reloaded.Stats().Hits != 2 {
+		t.Errorf("embedded %v after reloading, want both texts served from disk", inner.embedded)
+	}
+
+	// The modification time orders the entries, so alpha, used last on disk,
+	// is kept by a smaller cache.
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(cache.path(cache.key("beta")), old, old); err != nil {
+		t.Fatal(err)
+	}
+	smaller, inner := newCache(t, dir, CacheOptions{MaxEntries: 1})
+	if stats := smaller.Stats(); stats.Entries != 1 || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want the older entry evicted on load", stats)
+	}
+	embed(t, smaller, "alpha")
+	if len(inner.embedded) != 0 {
+		t.Errorf("embedded %v, want alpha kept", inner.embedded)
+	}
+	if _, err := os.Stat(cache.path(cache.key("beta"))); !os.IsNotExist(err) {
+		t.Errorf("the evicted entry is still on disk: %v", err)
+	}
+}
+
+func TestCacheRemovesCorruptEntries(t *testing.T) {
+	cache, inner := newCache(t, t.TempDir(), CacheOptions{})
+	embed(t, cache, "alpha")
+	path := cache.path(cache.key("alpha"))
+	if err := os.WriteFile(path, []byte{1, 2, 3}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	embed(t, cache, "alpha")
+	if want := []string{"alpha", "alpha"}; !reflect.DeepEqual(inner.embedded, want) {
+		t.Errorf("embedded %v, want the corrupt entry embedded again", inner.embedded)
+	}
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Misses != 2 || stats.Entries != 1 || stats.Bytes != 16 {
+		t.Errorf("stats = %+v, want the corrupt entry replaced", stats)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 16 {
+		t.Errorf("the entry on disk is %v, %v, want it rewritten", info, err)
+	}
+}
+
+func TestCacheRejectsWrongEmbeddingCount(t *testing.T) {
+	for _, extra := range []int{1, -1} {
+		cache, inner := newCache(t, t.TempDir(), CacheOptions{})
+		inner.extra = extra
+		_, err := cache.EmbedBatch(context.Background(), []string{"alpha", "beta"})
+		if err == nil || !strings.Contains(err.Error(), "embeddings for 2 texts") {
+			t.Errorf("with %+d embeddings, EmbedBatch returned %v", extra, err)
+		}
+		if stats := cache.Stats(); stats.Entries != 0 {
+			t.Errorf("with %+d embeddings, cached %d entries", extra, stats.Entries)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/configs"
+	"sync/atomic"
+)
+
+const (
+	ProviderGemini  = "gemini"
+	ProviderOpenAI  = "openai"
+	ProviderHashing = "hashing"
+)
+
+// Embedder turns text into vectors.
+type Embedder interface {
+	EmbedOne(ctx context.Context, text string) ([]float32, error)
+	// EmbedBatch returns one vector per text, in the same order.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	// Dimension is the number of features of the vectors, or 0 while it is not
+	// configured nor known from a previous call.
+	Dimension() int
+	ModelName() string
+}
+
+// New builds the embedder of the configured provider, behind the on-disk cache
+// when one is configured. Embedders holding resources implement io.Closer.
+func New(ctx context.Context, cfg configs.Config) (Embedder, error) {
+	embeddingConfig := cfg.EmbeddingConfig
+	provider := embeddingConfig.Provider
+	if provider == "" {
+		provider = ProviderGemini
+	}
+
+	var embedder Embedder
+	switch provider {
+	case ProviderGemini:
+		geminiEmbedder, err := NewGeminiEmbedder(ctx, cfg.GeminiConfig.APIKey, embeddingConfig.Model, embeddingConfig.Dimension)
+		if err != nil {
+			return nil, err
+		}
+		embedder = geminiEmbedder
+	case ProviderOpenAI:
+		embedder = NewOpenAIEmbedder(embeddingConfig.OpenAIConfig.BaseURL, embeddingConfig.OpenAIConfig.APIKey,
+			embeddingConfig.Model, embeddingConfig.Dimension)
+	case ProviderHashing:
+		embedder = NewHashingEmbedder(embeddingConfig.Dimension)
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", embeddingConfig.Provider)
+	}
+
+	cacheConfig := embeddingConfig.CacheConfig
+	if cacheConfig.Dir == "" {
+		return embedder, nil
+	}
+	return NewCachedEmbedder(embedder, provider, CacheOptions{
+		Dir:        cacheConfig.Dir,
+		MaxBytes:   cacheConfig.MaxBytes,
+		MaxEntries: cacheConfig.MaxEntries,
+	})
+}
+
+// dimension remembers the number of features of an embedder, either configured
+// or learned from its first response.
+type dimension struct {
+	value atomic.Int64
+}
+
+func (d *dimension) get() int {
+	return int(d.value.Load())
+}
+
+// observe checks the vectors against the known dimension, learning it if it
+// is not known yet.
+func (d *dimension) observe(vectors ...[]float32) error {
+	for _, vector := range vectors {
+		d.value.CompareAndSwap(0, int64(len(vector)))
+		if expected := d.get(); len(vector) != expected {
+			return fmt.Errorf("embedding has %d features, expected %d", len(vector), expected)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// geminiMaxBatchSize is the most texts BatchEmbedContents accepts per request.
+const geminiMaxBatchSize = 100
+
+// GeminiEmbedder embeds text with a Gemini embedding model.
+type GeminiEmbedder struct {
+	client    *genai.Client
+	model     *genai.EmbeddingModel
+	modelName string
+	dimension dimension
+}
+
+// NewGeminiEmbedder creates a Gemini client for the model. A zero dimension is
+// learned from the first embedding.
+func NewGeminiEmbedder(ctx context.Context, apiKey, modelName string, dimension int) (*GeminiEmbedder, error) {
+	if modelName == "" {
+		return nil, fmt.Errorf("gemini embedding model cannot be empty")
+	}
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &GeminiEmbedder{
+		client:    client,
+		model:     client.EmbeddingModel(modelName),
+		modelName: modelName,
+	}
+	e.dimension.value.Store(int64(dimension))
+	return e, nil
+}
+
+func (e *GeminiEmbedder) EmbedOne(ctx context.Context, text string) ([]float32, error) {
+	res, err := e.model.EmbedContent(ctx, genai.Text(text))
+	if err != nil {
+		return nil, err
+	}
+	if err := e.dimension.observe(res.Embedding.Values); err != nil {
+		return nil, err
+	}
+	return res.Embedding.Values, nil
+}
+
+func (e *GeminiEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	batch := e.model.NewBatch()
+	for _, text := range texts {
+		batch.AddContent(genai.Text(text))
+	}
+
+	res, err := e.model.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(res.Embeddings), len(texts))
+	}
+
+	vectors := make([][]float32, len(res.Embeddings))
+	for i, embedding := range res.Embeddings {
+		vectors[i] = embedding.Values
+	}
+	if err := e.dimension.observe(vectors...); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func (e *GeminiEmbedder) MaxBatchSize() int {
+	return geminiMaxBatchSize
+}
+
+func (e *GeminiEmbedder) Dimension() int {
+	return e.dimension.get()
+}
+
+func (e *GeminiEmbedder) ModelName() string {
+	return e.modelName
+}
+
+func (e *GeminiEmbedder) Close() error {
+	return e.client.Close()
+}
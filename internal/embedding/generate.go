@@ -0,0 +1,406 @@
+package embedding
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/time/rate"
+	"io"
+	"os"
+	"sync"
+)
+
+const defaultChunkSize = 100
+
+// BatchLimiter is implemented by embedders whose provider caps the number of
+// texts per EmbedBatch call.
+type BatchLimiter interface {
+	MaxBatchSize() int
+}
+
+type GenerateOptions struct {
+	// ChunkSize is the number of texts per EmbedBatch call. Defaults to the
+	// provider limit.
+	ChunkSize int
+	// Concurrency bounds the number of chunks embedded at once. Defaults to 1.
+	Concurrency int
+	// RequestsPerSecond rate limits the EmbedBatch calls. Zero disables it.
+	RequestsPerSecond float64
+	// CheckpointPath, when set, records every completed chunk so a failed run
+	// can be resumed without embedding those chunks again.
+	CheckpointPath string
+}
+
+// TextIterator yields the texts to embed. Next returns io.EOF after the last
+// one.
+type TextIterator interface {
+	Next() (string, error)
+}
+
+type sliceTextIterator struct {
+	texts []string
+	next  int
+}
+
+func (it *sliceTextIterator) Next() (string, error) {
+	if it.next >= len(it.texts) {
+		return "", io.EOF
+	}
+	text := it.texts[it.next]
+	it.next++
+	return text, nil
+}
+
+// Chunk is a run of consecutive texts and their vectors. First is the position
+// of the first text in the input, from 0.
+type Chunk struct {
+	First   int
+	Texts   []string
+	Vectors [][]float32
+}
+
+// Generate embeds the texts chunk by chunk and returns the vectors in the order
+// of the texts.
+func Generate(ctx context.Context, embedder Embedder, texts []string, opts GenerateOptions) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	err := Stream(ctx, embedder, &sliceTextIterator{texts: texts}, opts, func(chunk Chunk) error {
+		vectors = append(vectors, chunk.Vectors...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+// Stream embeds the texts chunk by chunk and passes every chunk to emit, in
+// the order of the texts. Texts are read as chunks are needed and a chunk is
+// dropped once emitted, so only the chunks being embedded, or waiting for an
+// earlier one to be, are held in memory.
+func Stream(ctx context.Context, embedder Embedder, texts TextIterator, opts GenerateOptions, emit func(Chunk) error) error {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+		if limiter, ok := embedder.(BatchLimiter); ok && limiter.MaxBatchSize() > 0 {
+			chunkSize = limiter.MaxBatchSize()
+		}
+	}
+	concurrency := max(opts.Concurrency, 1)
+	// window bounds the chunks read but not emitted yet, so a slow chunk
+	// cannot make the others pile up.
+	window := 2 * concurrency
+
+	var checkpoint *checkpointFile
+	if opts.CheckpointPath != "" {
+		var err error
+		checkpoint, err = openCheckpoint(opts.CheckpointPath, fingerprint(embedder.ModelName(), chunkSize))
+		if err != nil {
+			return err
+		}
+		defer checkpoint.close()
+	}
+
+	var limiter *rate.Limiter
+	if opts.RequestsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.RequestsPerSecond), 1)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		chunk Chunk
+		err   error
+	}
+	var (
+		jobs    = make(chan result)
+		results = make(chan result)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				var err error
+				if limiter != nil {
+					err = limiter.Wait(ctx)
+				}
+				if err == nil {
+					job.chunk.Vectors, err = embedder.EmbedBatch(ctx, job.chunk.Texts)
+					if err != nil {
+						err = fmt.Errorf("embedding lines %d-%d: %w", job.chunk.First+1, job.chunk.First+len(job.chunk.Texts), err)
+					}
+				}
+				if err == nil && checkpoint != nil {
+					err = checkpoint.record(job.index, textsHash(job.chunk.Texts), job.chunk.Vectors)
+				}
+				job.err = err
+
+				select {
+				case results <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	defer func() {
+		cancel()
+		close(jobs)
+		wg.Wait()
+	}()
+
+	var (
+		read     int
+		eof      bool
+		ready    = make(map[int]Chunk)
+		next     int
+		inFlight int
+		job      *result
+	)
+	for {
+		for chunk, ok := ready[next]; ok; chunk, ok = ready[next] {
+			delete(ready, next)
+			if err := emit(chunk); err != nil {
+				return err
+			}
+			next++
+			inFlight--
+		}
+		if eof && inFlight == 0 {
+			break
+		}
+
+		if job == nil && !eof && inFlight < window {
+			chunk := Chunk{First: read * chunkSize}
+			for len(chunk.Texts) < chunkSize {
+				text, err := texts.Next()
+				if errors.Is(err, io.EOF) {
+					eof = true
+					break
+				}
+				if err != nil {
+					return err
+				}
+				chunk.Texts = append(chunk.Texts, text)
+			}
+			if len(chunk.Texts) == 0 {
+				continue
+			}
+			index := read
+			read++
+			inFlight++
+
+			if checkpoint != nil {
+				vectors, ok, err := checkpoint.completed(index, textsHash(chunk.Texts), len(chunk.Texts))
+				if err != nil {
+					return fmt.Errorf("checkpoint %s: %w", opts.CheckpointPath, err)
+				}
+				if ok {
+					chunk.Vectors = vectors
+					ready[index] = chunk
+					continue
+				}
+			}
+			job = &result{index: index, chunk: chunk}
+		}
+
+		var send chan<- result
+		var pending result
+		if job != nil {
+			send, pending = jobs, *job
+		}
+		select {
+		case send <- pending:
+			job = nil
+		case r := <-results:
+			if r.err != nil {
+				return r.err
+			}
+			ready[r.index] = r.chunk
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if checkpoint != nil && checkpoint.lastChunk() >= read {
+		return fmt.Errorf("checkpoint %s has more chunks than the input, delete it to start over", opts.CheckpointPath)
+	}
+	return nil
+}
+
+// fingerprint identifies the model and chunking a checkpoint was written with.
+// The texts of every chunk are checked against their own hash.
+func fingerprint(modelName string, chunkSize int) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%d\n", modelName, chunkSize)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func textsHash(texts []string) string {
+	h := sha256.New()
+	for _, text := range texts {
+		fmt.Fprintf(h, "%d:%s", len(text), text)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type checkpointHeader struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+type checkpointRecord struct {
+	Chunk int `json:"chunk"`
+	// Hash is the textsHash of the texts of the chunk, so a chunk is never
+	// resumed for other texts.
+	Hash    string      `json:"hash"`
+	Vectors [][]float32 `json:"vectors"`
+}
+
+// checkpointLocation is where a completed chunk is recorded in the checkpoint
+// file. Only locations are kept in memory, a chunk is read back when its turn
+// to be emitted comes.
+type checkpointLocation struct {
+	offset int64
+	length int
+	hash   string
+}
+
+// checkpointFile is a JSON Lines file: a header followed by one record per
+// completed chunk, in completion order.
+type checkpointFile struct {
+	mu        sync.Mutex
+	file      *os.File
+	size      int64
+	chunks    map[int]checkpointLocation
+	maxLoaded int
+}
+
+func openCheckpoint(path, fingerprint string) (*checkpointFile, error) {
+	// Rewrite the checkpoint with the records that could be read, so a record
+	// truncated by a crash does not corrupt the ones appended after it.
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &checkpointFile{file: file, chunks: make(map[int]checkpointLocation), maxLoaded: -1}
+
+	err = checkpoint.writeLine(checkpointHeader{Fingerprint: fingerprint})
+	if err == nil {
+		err = checkpoint.copyFrom(path, fingerprint)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// copyFrom appends the readable records of the checkpoint at path, if any.
+func (c *checkpointFile) copyFrom(path, fingerprint string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1<<20), 1<<30)
+
+	var header checkpointHeader
+	if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &header) != nil {
+		return fmt.Errorf("checkpoint %s is corrupted, delete it to start over", path)
+	}
+	if header.Fingerprint != fingerprint {
+		return fmt.Errorf("checkpoint %s was written for another model or chunk size, delete it to start over", path)
+	}
+	for scanner.Scan() {
+		var record checkpointRecord
+		// A crash while writing can leave a truncated record, that chunk is
+		// simply embedded again.
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+		if err := c.writeLine(record); err != nil {
+			return err
+		}
+		c.maxLoaded = max(c.maxLoaded, record.Chunk)
+	}
+	return scanner.Err()
+}
+
+// completed returns the vectors recorded for the chunk, if any. It fails when
+// the chunk was recorded for other texts.
+func (c *checkpointFile) completed(chunk int, hash string, numTexts int) ([][]float32, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	location, ok := c.chunks[chunk]
+	if !ok {
+		return nil, false, nil
+	}
+	if location.hash != hash {
+		return nil, false, fmt.Errorf("chunk %d was embedded from other texts, delete it to start over", chunk)
+	}
+
+	line := make([]byte, location.length)
+	if _, err := c.file.ReadAt(line, location.offset); err != nil {
+		return nil, false, err
+	}
+	var record checkpointRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, false, err
+	}
+	if len(record.Vectors) != numTexts {
+		return nil, false, fmt.Errorf("chunk %d has %d vectors for %d texts", chunk, len(record.Vectors), numTexts)
+	}
+	delete(c.chunks, chunk)
+	return record.Vectors, true, nil
+}
+
+// lastChunk returns the highest chunk the checkpoint held when opened, -1 if
+// none.
+func (c *checkpointFile) lastChunk() int {
+	return c.maxLoaded
+}
+
+func (c *checkpointFile) record(chunk int, hash string, vectors [][]float32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writeLine(checkpointRecord{Chunk: chunk, Hash: hash, Vectors: vectors})
+}
+
+// writeLine appends a line to the file and, for records, remembers where.
+// Callers hold mu or own the checkpoint exclusively.
+func (c *checkpointFile) writeLine(value any) error {
+	line, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, err := c.file.WriteAt(append(line, '\n'), c.size); err != nil {
+		return err
+	}
+	if record, ok := value.(checkpointRecord); ok {
+		c.chunks[record.Chunk] = checkpointLocation{offset: c.size, length: len(line), hash: record.Hash}
+	}
+	c.size += int64(len(line)) + 1
+	return nil
+}
+
+func (c *checkpointFile) close() {
+	c.file.Close()
+}
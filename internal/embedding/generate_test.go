@@ -0,0 +1,170 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingEmbedder embeds like a HashingEmbedder after a random delay, so
+// chunks complete out of order, and records the texts it embedded. It fails
+// every chunk holding failText.
+type recordingEmbedder struct {
+	*HashingEmbedder
+	failText string
+
+	mu       sync.Mutex
+	embedded []string
+}
+
+func (e *recordingEmbedder) ModelName() string { return "recording" }
+
+func (e *recordingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	time.Sleep(time.Duration(rand.IntN(2000)) * time.Microsecond)
+	for _, text := range texts {
+		if text == e.failText {
+			return nil, errors.New("quota exceeded")
+		}
+	}
+	e.mu.Lock()
+	e.embedded = append(e.embedded, texts...)
+	e.mu.Unlock()
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.EmbedOne(ctx, text)
+	}
+	return vectors, nil
+}
+
+func testTexts(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text number %d", i)
+	}
+	return texts
+}
+
+func TestStreamEmitsChunksInOrder(t *testing.T) {
+	texts := testTexts(103)
+	embedder := &recordingEmbedder{HashingEmbedder: NewHashingEmbedder(8)}
+
+	var (
+		emitted []string
+		firsts  []int
+	)
+	err := Stream(context.Background(), embedder, &sliceTextIterator{texts: texts},
+		GenerateOptions{ChunkSize: 10, Concurrency: 4},
+		func(chunk Chunk) error {
+			if len(chunk.Texts) != len(chunk.Vectors) {
+				t.Errorf("chunk at %d has %d texts and %d vectors", chunk.First, len(chunk.Texts), len(chunk.Vectors))
+			}
+			firsts = append(firsts, chunk.First)
+			emitted = append(emitted, chunk.Texts...)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(emitted, texts) {
+		t.Errorf("emitted the texts out of order: %v", emitted)
+	}
+	if want := []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}; !reflect.DeepEqual(firsts, want) {
+		t.Errorf("chunks started at %v, want %v", firsts, want)
+	}
+}
+
+func TestGenerateMatchesEmbedOne(t *testing.T) {
+	texts := testTexts(25)
+	embedder := &recordingEmbedder{HashingEmbedder: NewHashingEmbedder(8)}
+	vectors, err := Generate(context.Background(), embedder, texts, GenerateOptions{ChunkSize: 7, Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range texts {
+		want, _ := embedder.EmbedOne(context.Background(), text)
+		if !reflect.DeepEqual(vectors[i], want) {
+			t.Fatalf("vector %d does not embed %q", i, text)
+		}
+	}
+
+	if vectors, err := Generate(context.Background(), embedder, nil, GenerateOptions{}); err != nil || len(vectors) != 0 {
+		t.Errorf("Generate(nil) = %v, %v, want no vectors", vectors, err)
+	}
+}
+
+func TestStreamEmitErrorStops(t *testing.T) {
+	embedder := &recordingEmbedder{HashingEmbedder: NewHashingEmbedder(8)}
+	errFull := errors.New("disk full")
+	err := Stream(context.Background(), embedder, &sliceTextIterator{texts: testTexts(100)},
+		GenerateOptions{ChunkSize: 10, Concurrency: 2},
+		func(Chunk) error { return errFull })
+	if !errors.Is(err, errFull) {
+		t.Fatalf("Stream returned %v, want the emit error", err)
+	}
+	// The window bounds how far embedding runs ahead of a failed emit.
+	if n := len(embedder.embedded); n > 50 {
+		t.Errorf("embedded %d texts after the first emit failed", n)
+	}
+}
+
+func TestStreamResumesFromCheckpoint(t *testing.T) {
+	texts := testTexts(50)
+	checkpointPath := filepath.Join(t.TempDir(), "embeddings.checkpoint")
+	opts := GenerateOptions{ChunkSize: 5, Concurrency: 2, CheckpointPath: checkpointPath}
+
+	failing := &recordingEmbedder{HashingEmbedder: NewHashingEmbedder(8), failText: texts[32]}
+	err := Stream(context.Background(), failing, &sliceTextIterator{texts: texts}, opts, func(Chunk) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "embedding lines 31-35: quota exceeded") {
+		t.Fatalf("the first run returned %v, want the failure of lines 31-35", err)
+	}
+
+	resumed := &recordingEmbedder{HashingEmbedder: NewHashingEmbedder(8)}
+	vectors, err := Generate(context.Background(), resumed, texts, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range texts {
+		want, _ := resumed.EmbedOne(context.Background(), text)
+		if !reflect.DeepEqual(vectors[i], want) {
+			t.Fatalf("vector %d does not embed %q", i, text)
+		}
+	}
+	if len(resumed.embedded)+len(failing.embedded) != len(texts) {
+		t.Errorf("embedded %d texts then %d more, want the %d texts embedded once", len(failing.embedded), len(resumed.embedded), len(texts))
+	}
+}
+
+func TestStreamRejectsCheckpointOfOtherTexts(t *testing.T) {
+	checkpointPath := filepath.Join(t.TempDir(), "embeddings.checkpoint")
+	opts := GenerateOptions{ChunkSize: 5, CheckpointPath: checkpointPath}
+	embedder := &recordingEmbedder{HashingEmbedder: NewHashingEmbedder(8)}
+
+	if _, err := Generate(context.Background(), embedder, testTexts(10), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := testTexts(10)
+	changed[7] = "another text"
+	_, err := Generate(context.Background(), embedder, changed, opts)
+	if err == nil || !strings.Contains(err.Error(), "chunk 1 was embedded from other texts") {
+		t.Errorf("resuming with other texts returned %v", err)
+	}
+
+	_, err = Generate(context.Background(), embedder, testTexts(5), opts)
+	if err == nil || !strings.Contains(err.Error(), "more chunks than the input") {
+		t.Errorf("resuming with fewer texts returned %v", err)
+	}
+
+	_, err = Generate(context.Background(), embedder, testTexts(10), GenerateOptions{ChunkSize: 2, CheckpointPath: checkpointPath})
+	if err == nil || !strings.Contains(err.Error(), "another model or chunk size") {
+		t.Errorf("resuming with another chunk size returned %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const defaultHashingDimension = 128
+
+// HashingEmbedder is a deterministic embedder for tests: every lowercased word
+// is hashed into a signed bucket and the resulting vector is L2 normalized.
+// Texts sharing words end up close to each other.
+type HashingEmbedder struct {
+	dimension int
+}
+
+// NewHashingEmbedder returns an embedder producing vectors with the given
+// number of features, 128 when zero.
+func NewHashingEmbedder(dimension int) *HashingEmbedder {
+	if dimension <= 0 {
+		dimension = defaultHashingDimension
+	}
+	return &HashingEmbedder{dimension: dimension}
+}
+
+func (e *HashingEmbedder) EmbedOne(_ context.Context, text string) ([]float32, error) {
+	vector := make([]float32, e.dimension)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+
+		bucket := sum % uint64(e.dimension)
+		if sum&(1<<63) != 0 {
+			vector[bucket]--
+		} else {
+			vector[bucket]++
+		}
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector, nil
+}
+
+func (e *HashingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector, err := e.EmbedOne(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func (e *HashingEmbedder) Dimension() int {
+	return e.dimension
+}
+
+func (e *HashingEmbedder) ModelName() string {
+	return fmt.Sprintf("hashing-%d", e.dimension)
+}
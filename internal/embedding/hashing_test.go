@@ -0,0 +1,58 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestHashingEmbedder(t *testing.T) {
+	ctx := context.Background()
+	embedder := NewHashingEmbedder(16)
+	if embedder.Dimension() != 16 || embedder.ModelName() != "hashing-16" {
+		t.Errorf("dimension %d and model %q, want 16 and hashing-16", embedder.Dimension(), embedder.ModelName())
+	}
+	if got := NewHashingEmbedder(0).Dimension(); got != defaultHashingDimension {
+		t.Errorf("NewHashingEmbedder(0) has dimension %d, want %d", got, defaultHashingDimension)
+	}
+
+	texts := []string{"The quick brown fox", "the QUICK, brown fox!", "a lazy dog"}
+	vectors, err := embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, vector := range vectors {
+		if len(vector) != 16 {
+			t.Fatalf("%q has %d features, want 16", texts[i], len(vector))
+		}
+		var norm float64
+		for _, value := range vector {
+			norm += float64(value) * float64(value)
+		}
+		if math.Abs(norm-1) > 1e-5 {
+			t.Errorf("%q has a squared norm of %v, want 1", texts[i], norm)
+		}
+		again, _ := NewHashingEmbedder(16).EmbedOne(ctx, texts[i])
+		if !reflect.DeepEqual(again, vector) {
+			t.Errorf("%q embeds to %v then %v", texts[i], vector, again)
+		}
+	}
+	// Case and punctuation do not change the words.
+	if !reflect.DeepEqual(vectors[0], vectors[1]) {
+		t.Errorf("%q and %q embed differently", texts[0], texts[1])
+	}
+	if reflect.DeepEqual(vectors[0], vectors[2]) {
+		t.Errorf("%q and %q embed the same", texts[0], texts[2])
+	}
+
+	empty, err := embedder.EmbedOne(ctx, "")
+	if err != nil || len(empty) != 16 {
+		t.Fatalf("EmbedOne(\"\") = %v, %v", empty, err)
+	}
+	for _, value := range empty {
+		if value != 0 {
+			t.Fatalf("EmbedOne(\"\") = %v, want the zero vector", empty)
+		}
+	}
+}
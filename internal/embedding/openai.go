@@ -0,0 +1,114 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint, as served by
+// OpenAI itself but also by llama.cpp or Ollama.
+type OpenAIEmbedder struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	modelName  string
+	dimension  dimension
+}
+
+// NewOpenAIEmbedder targets baseURL, e.g. "http://localhost:11434/v1". The API
+// key is optional for local servers. A zero dimension is learned from the
+// first embedding.
+func NewOpenAIEmbedder(baseURL, apiKey, modelName string, dimension int) *OpenAIEmbedder {
+	e := &OpenAIEmbedder{
+		httpClient: &http.Client{Timeout: time.Minute},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		modelName:  modelName,
+	}
+	e.dimension.value.Store(int64(dimension))
+	return e
+}
+
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *OpenAIEmbedder) EmbedOne(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(openAIEmbeddingRequest{
+		Model: e.modelName,
+		Input: texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("embedding request failed with status %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	var embeddingResponse openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingResponse); err != nil {
+		return nil, fmt.Errorf("decoding embedding response: %w", err)
+	}
+	if len(embeddingResponse.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(embeddingResponse.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range embeddingResponse.Data {
+		if data.Index < 0 || data.Index >= len(texts) || vectors[data.Index] != nil {
+			return nil, fmt.Errorf("unexpected embedding index %d", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	if err := e.dimension.observe(vectors...); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func (e *OpenAIEmbedder) Dimension() int {
+	return e.dimension.get()
+}
+
+func (e *OpenAIEmbedder) ModelName() string {
+	return e.modelName
+}
@@ -0,0 +1,119 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type openAIData struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// openAIServer serves /embeddings with respond, after checking the request.
+func openAIServer(t *testing.T, respond func(w http.ResponseWriter, input []string)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/embeddings" {
+			t.Errorf("got %s %s, want POST /v1/embeddings", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want the bearer key", got)
+		}
+		var request openAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decoding the request: %v", err)
+		}
+		if request.Model != "test-model" {
+			t.Errorf("model = %q, want test-model", request.Model)
+		}
+		respond(w, request.Input)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAIEmbedderReordersByIndex(t *testing.T) {
+	server := openAIServer(t, func(w http.ResponseWriter, input []string) {
+		// Answer in reverse order, the index tells which text each vector is of.
+		var data []openAIData
+		for i := len(input) - 1; i >= 0; i-- {
+			data = append(data, openAIData{Index: i, Embedding: []float32{float32(i), float32(len(input[i]))}})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+
+	embedder := NewOpenAIEmbedder(server.URL+"/v1/", "secret", "test-model", 0)
+	vectors, err := embedder.EmbedBatch(context.Background(), []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float32{{0, 1}, {1, 2}, {2, 3}}; !reflect.DeepEqual(vectors, want) {
+		t.Errorf("EmbedBatch = %v, want %v", vectors, want)
+	}
+	if embedder.Dimension() != 2 || embedder.ModelName() != "test-model" {
+		t.Errorf("dimension %d and model %q, want 2 learned and test-model", embedder.Dimension(), embedder.ModelName())
+	}
+}
+
+func TestOpenAIEmbedderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(w http.ResponseWriter, input []string)
+		want    string
+	}{
+		{
+			name: "status",
+			respond: func(w http.ResponseWriter, _ []string) {
+				http.Error(w, "rate limited", http.StatusTooManyRequests)
+			},
+			want: "status 429 Too Many Requests: rate limited",
+		},
+		{
+			name: "fewer embeddings",
+			respond: func(w http.ResponseWriter, _ []string) {
+				json.NewEncoder(w).Encode(map[string]any{"data": []openAIData{{Index: 0, Embedding: []float32{1}}}})
+			},
+			want: "got 1 embeddings for 2 texts",
+		},
+		{
+			name: "duplicate index",
+			respond: func(w http.ResponseWriter, _ []string) {
+				json.NewEncoder(w).Encode(map[string]any{"data": []openAIData{
+					{Index: 1, Embedding: []float32{1}}, {Index: 1, Embedding: []float32{2}},
+				}})
+			},
+			want: "unexpected embedding index 1",
+		},
+		{
+			name: "invalid json",
+			respond: func(w http.ResponseWriter, _ []string) {
+				w.Write([]byte("{"))
+			},
+			want: "decoding embedding response",
+		},
+	}
+	for _, test := range tests {
+		server := openAIServer(t, test.respond)
+		embedder := NewOpenAIEmbedder(server.URL+"/v1", "secret", "test-model", 0)
+		_, err := embedder.EmbedBatch(context.Background(), []string{"a", "b"})
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: EmbedBatch returned %v, want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestOpenAIEmbedderChecksDimension(t *testing.T) {
+	server := openAIServer(t, func(w http.ResponseWriter, input []string) {
+		json.NewEncoder(w).Encode(map[string]any{"data": []openAIData{{Index: 0, Embedding: []float32{1, 2}}}})
+	})
+	embedder := NewOpenAIEmbedder(server.URL+"/v1", "secret", "test-model", 3)
+	if _, err := embedder.EmbedOne(context.Background(), "a"); err == nil || !strings.Contains(err.Error(), "expected 3") {
+		t.Errorf("EmbedOne returned %v, want the configured dimension enforced", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package evaluation measures the accuracy and latency of MuopDB searches
+// against exact nearest neighbors computed locally by brute force.
+package evaluation
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/vectorfile"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+type Metric string
+
+const (
+	// MetricL2 ranks by smallest euclidean distance.
+	MetricL2 Metric = "l2"
+	// MetricDot ranks by largest dot product.
+	MetricDot Metric = "dot"
+)
+
+// baseChunkSize is the number of base vectors held in memory at once.
+const baseChunkSize = 10000
+
+func ParseMetric(s string) (Metric, error) {
+	switch Metric(s) {
+	case MetricL2, MetricDot:
+		return Metric(s), nil
+	default:
+		return "", fmt.Errorf("unknown metric %q, expected %q or %q", s, MetricL2, MetricDot)
+	}
+}
+
+// distance is lower for closer vectors under both metrics.
+func (m Metric) distance(a, b []float32) float32 {
+	var d float32
+	if m == MetricDot {
+		for i := range a {
+			d -= a[i] * b[i]
+		}
+		return d
+	}
+	for i := range a {
+		diff := a[i] - b[i]
+		d += diff * diff
+	}
+	return d
+}
+
+type neighbor struct {
+	id       muopdbclient.DocID
+	distance float32
+}
+
+// neighborHeap keeps the k closest neighbors seen so far, farthest on top.
+type neighborHeap []neighbor
+
+func (h neighborHeap) Len() int           { return len(h) }
+func (h neighborHeap) Less(i, j int) bool { return h[i].distance > h[j].distance }
+func (h neighborHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *neighborHeap) Push(x any)        { *h = append(*h, x.(neighbor)) }
+func (h *neighborHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+func (h *neighborHeap) offer(k int, n neighbor) {
+	if h.Len() < k {
+		heap.Push(h, n)
+		return
+	}
+	if n.distance < (*h)[0].distance {
+		(*h)[0] = n
+		heap.Fix(h, 0)
+	}
+}
+
+// sorted returns the neighbors closest first.
+func (h neighborHeap) sorted() []muopdbclient.DocID {
+	neighbors := append(neighborHeap{}, h...)
+	sort.SliceStable(neighbors, func(i, j int) bool { return neighbors[i].distance < neighbors[j].distance })
+	ids := make([]muopdbclient.DocID, len(neighbors))
+	for i, n := range neighbors {
+		ids[i] = n.id
+	}
+	return ids
+}
+
+// GroundTruth returns the ids of the k exact nearest base records of every
+// query, closest first. The base is streamed a chunk at a time, so only the
+// queries and their k best candidates are held in memory.
+func GroundTruth(base *vectorfile.Reader, queries [][]float32, k int, metric Metric) ([][]muopdbclient.DocID, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+	for q, query := range queries {
+		if len(query) != len(queries[0]) {
+			return nil, fmt.Errorf("query %d has dimension %d but query 0 has %d", q, len(query), len(queries[0]))
+		}
+	}
+
+	heaps := make([]neighborHeap, len(queries))
+	workers := min(runtime.NumCPU(), max(len(queries), 1))
+
+	chunk := make([]vectorfile.Record, 0, baseChunkSize)
+	scan := func() {
+		var wg sync.WaitGroup
+		// Every worker owns a disjoint set of queries, so no heap is shared.
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for q := w; q < len(queries); q += workers {
+					for _, record := range chunk {
+						heaps[q].offer(k, neighbor{id: record.ID, distance: metric.distance(queries[q], record.Vector)})
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		chunk = chunk[:0]
+	}
+
+	for {
+		record, err := base.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(queries) > 0 && len(record.Vector) != len(queries[0]) {
+			return nil, fmt.Errorf("base record %s has dimension %d but the queries have %d",
+				record.ID, len(record.Vector), len(queries[0]))
+		}
+		chunk = append(chunk, record)
+		if len(chunk) == baseChunkSize {
+			scan()
+		}
+	}
+	scan()
+
+	truth := make([][]muopdbclient.DocID, len(queries))
+	for q := range heaps {
+		truth[q] = heaps[q].sorted()
+	}
+	return truth, nil
+}
+
+// LoadGroundTruth reads precomputed neighbors, such as the .ivecs files shipped
+// with the ANN benchmarks, where every record lists base record numbers.
+func LoadGroundTruth(path string) ([][]muopdbclient.DocID, error) {
+	format, err := vectorfile.DetectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == vectorfile.FormatIvecs {
+		rows, err := vectorfile.LoadIvecs(path)
+		if err != nil {
+			return nil, err
+		}
+		truth := make([][]muopdbclient.DocID, len(rows))
+		for q, row := range rows {
+			truth[q] = make([]muopdbclient.DocID, len(row))
+			for i, value := range row {
+				if value < 0 {
+					return nil, fmt.Errorf("%s: record %d lists negative neighbor %d", path, q+1, value)
+				}
+				truth[q][i] = muopdbclient.NewDocIDFromUint64(uint64(value))
+			}
+		}
+		return truth, nil
+	}
+
+	// The other formats hold float32 components, exact up to 2^24 only.
+	records, err := vectorfile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	truth := make([][]muopdbclient.DocID, len(records))
+	for q, record := range records {
+		truth[q] = make([]muopdbclient.DocID, len(record.Vector))
+		for i, value := range record.Vector {
+			if value < 0 || value != float32(math.Trunc(float64(value))) {
+				return nil, fmt.Errorf("%s: record %d lists invalid neighbor %v", path, q+1, value)
+			}
+			if value >= 1<<24 {
+				return nil, fmt.Errorf("%s: record %d lists neighbor %v, beyond the record numbers a %s file holds exactly, use an .ivecs file",
+					path, q+1, value, format)
+			}
+			truth[q][i] = muopdbclient.NewDocIDFromUint64(uint64(value))
+		}
+	}
+	return truth, nil
+}
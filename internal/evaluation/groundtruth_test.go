@@ -0,0 +1,132 @@
+package evaluation
+
+import (
+	"encoding/binary"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/vectorfile"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroundTruth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "base.fvecs")
+	base := [][]float32{{0, 0}, {1, 0}, {3, 0}, {-2, 0}}
+	records := make([]vectorfile.Record, len(base))
+	for i, vector := range base {
+		records[i] = vectorfile.Record{Vector: vector}
+	}
+	if err := vectorfile.Save(path, records); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		metric Metric
+		want   [][]uint64
+	}{
+		{metric: MetricL2, want: [][]uint64{{0, 1, 3}, {2, 1, 0}}},
+		{metric: MetricDot, want: [][]uint64{{2, 1, 0}, {2, 1, 0}}},
+	}
+	for _, test := range tests {
+		reader, err := vectorfile.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		truth, err := GroundTruth(reader, [][]float32{{0.1, 0}, {3, 0}}, 3, test.metric)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([][]uint64, len(truth))
+		for q := range truth {
+			for _, id := range truth[q] {
+				got[q] = append(got[q], id.Low)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s ground truth = %v, want %v", test.metric, got, test.want)
+		}
+	}
+
+	reader, err := vectorfile.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if _, err := GroundTruth(reader, [][]float32{{0, 0, 0}}, 1, MetricL2); err == nil {
+		t.Error("GroundTruth accepted queries of another dimension than the base")
+	}
+	if _, err := GroundTruth(reader, nil, 0, MetricL2); err == nil {
+		t.Error("GroundTruth accepted k = 0")
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	for _, metric := range []Metric{MetricL2, MetricDot} {
+		if got, err := ParseMetric(string(metric)); err != nil || got != metric {
+			t.Errorf("ParseMetric(%q) = %q, %v", metric, got, err)
+		}
+	}
+	if _, err := ParseMetric("cosine"); err == nil {
+		t.Error("ParseMetric accepted cosine")
+	}
+}
+
+// writeIvecs writes rows of int32, which the vectorfile writer only takes as
+// float32.
+func writeIvecs(t *testing.T, path string, rows [][]int32) {
+	t.Helper()
+	var data []byte
+	for _, row := range rows {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(row)))
+		for _, value := range row {
+			data = binary.LittleEndian.AppendUint32(data, uint32(value))
+		}
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadGroundTruth(t *testing.T) {
+	dir := t.TempDir()
+	// 2^24+1 is the first integer a float32 rounds.
+	path := filepath.Join(dir, "groundtruth.ivecs")
+	writeIvecs(t, path, [][]int32{{16777217, 3}, {0, math.MaxInt32}})
+	truth, err := LoadGroundTruth(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]muopdbclient.DocID{
+		{muopdbclient.NewDocIDFromUint64(16777217), muopdbclient.NewDocIDFromUint64(3)},
+		{muopdbclient.NewDocIDFromUint64(0), muopdbclient.NewDocIDFromUint64(math.MaxInt32)},
+	}
+	if !reflect.DeepEqual(truth, want) {
+		t.Errorf("LoadGroundTruth = %v, want %v", truth, want)
+	}
+
+	negative := filepath.Join(dir, "negative.ivecs")
+	writeIvecs(t, negative, [][]int32{{1, -1}})
+	if _, err := LoadGroundTruth(negative); err == nil {
+		t.Error("LoadGroundTruth accepted a negative neighbor")
+	}
+
+	// Other formats only hold ids exactly up to 2^24.
+	small := filepath.Join(dir, "groundtruth.jsonl")
+	if err := vectorfile.Save(small, []vectorfile.Record{{Vector: []float32{2, 16777215}}}); err != nil {
+		t.Fatal(err)
+	}
+	if truth, err := LoadGroundTruth(small); err != nil || truth[0][1] != muopdbclient.NewDocIDFromUint64(16777215) {
+		t.Errorf("LoadGroundTruth(%s) = %v, %v", small, truth, err)
+	}
+	large := filepath.Join(dir, "large.jsonl")
+	if err := vectorfile.Save(large, []vectorfile.Record{{Vector: []float32{16777216}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGroundTruth(large); err == nil || !strings.Contains(err.Error(), "use an .ivecs file") {
+		t.Errorf("LoadGroundTruth(%s) returned %v, want an error pointing to .ivecs", large, err)
+	}
+}
@@ -0,0 +1,62 @@
+package evaluation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: time.Millisecond},
+		{p: 1, want: time.Millisecond},
+		{p: 50, want: 50 * time.Millisecond},
+		{p: 90, want: 90 * time.Millisecond},
+		{p: 99, want: 99 * time.Millisecond},
+		{p: 99.9, want: 100 * time.Millisecond},
+		{p: 100, want: 100 * time.Millisecond},
+	}
+	for _, test := range tests {
+		if got := Percentile(sorted, test.p); got != test.want {
+			t.Errorf("Percentile(1..100ms, %v) = %v, want %v", test.p, got, test.want)
+		}
+	}
+
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile(nil, 50) = %v, want 0", got)
+	}
+	if got := Percentile([]time.Duration{time.Second}, 99.9); got != time.Second {
+		t.Errorf("Percentile([1s], 99.9) = %v, want 1s", got)
+	}
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	latencies := []time.Duration{4 * time.Millisecond, time.Millisecond, 3 * time.Millisecond, 2 * time.Millisecond}
+	got := SummarizeLatencies(latencies)
+	want := LatencySummary{
+		Count: 4,
+		Mean:  2500 * time.Microsecond,
+		Min:   time.Millisecond,
+		P50:   2 * time.Millisecond,
+		P90:   4 * time.Millisecond,
+		P99:   4 * time.Millisecond,
+		P999:  4 * time.Millisecond,
+		Max:   4 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("SummarizeLatencies(%v) = %+v, want %+v", latencies, got, want)
+	}
+	if latencies[0] != 4*time.Millisecond {
+		t.Error("SummarizeLatencies sorted its argument")
+	}
+
+	if got := SummarizeLatencies(nil); got != (LatencySummary{}) {
+		t.Errorf("SummarizeLatencies(nil) = %+v, want the zero summary", got)
+	}
+}
@@ -0,0 +1,115 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"time"
+)
+
+type SearchOptions struct {
+	CollectionName string
+	TopK           uint32
+	EfConstruction uint32
+	UserIds        []muopdbclient.DocID
+}
+
+// Report aggregates the accuracy of the queries that succeeded. Failed queries
+// only count towards Errors.
+type Report struct {
+	Queries int `json:"queries"`
+	Errors  int `json:"errors"`
+	K       int `json:"k"`
+	// Recall is the mean fraction of the exact top k found among the results.
+	Recall float64 `json:"recall_at_k"`
+	// MRR is the mean reciprocal rank of the exact nearest neighbor in the
+	// results, zero for the queries that missed it.
+	MRR     float64        `json:"mrr"`
+	Latency LatencySummary `json:"latency"`
+}
+
+// Recall returns the fraction of truth found in results.
+func Recall(results, truth []muopdbclient.DocID) float64 {
+	if len(truth) == 0 {
+		return 1
+	}
+	relevant := make(map[muopdbclient.DocID]struct{}, len(truth))
+	for _, id := range truth {
+		relevant[id] = struct{}{}
+	}
+	found := 0
+	for _, id := range results {
+		if _, ok := relevant[id]; ok {
+			found++
+			delete(relevant, id)
+		}
+	}
+	return float64(found) / float64(len(truth))
+}
+
+// ReciprocalRank returns 1/rank of the nearest neighbor in results, or zero.
+func ReciprocalRank(results, truth []muopdbclient.DocID) float64 {
+	if len(truth) == 0 {
+		return 0
+	}
+	for i, id := range results {
+		if id == truth[0] {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// Evaluate runs every query through Search one at a time and compares the
+// results with the exact neighbors in truth, truncated to opts.TopK.
+func Evaluate(ctx context.Context, client muopdbclient.MuopDbClient, opts SearchOptions,
+	queries [][]float32, truth [][]muopdbclient.DocID) (Report, error) {
+	if len(truth) < len(queries) {
+		return Report{}, fmt.Errorf("ground truth covers %d queries out of %d", len(truth), len(queries))
+	}
+
+	report := Report{Queries: len(queries), K: int(opts.TopK)}
+	latencies := make([]time.Duration, 0, len(queries))
+	var (
+		recallSum, rrSum float64
+		firstErr         error
+	)
+
+	for q, query := range queries {
+		if err := ctx.Err(); err != nil {
+			return Report{}, err
+		}
+
+		start := time.Now()
+		response, err := client.Search(ctx, muopdbclient.SearchRequest{
+			CollectionName: opts.CollectionName,
+			Vector:         query,
+			TopK:           opts.TopK,
+			EfConstruction: opts.EfConstruction,
+			UserIds:        opts.UserIds,
+		})
+		latency := time.Since(start)
+		if err != nil {
+			report.Errors++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		latencies = append(latencies, latency)
+
+		expected := truth[q][:min(len(truth[q]), int(opts.TopK))]
+		recallSum += Recall(response.DocIds, expected)
+		rrSum += ReciprocalRank(response.DocIds, expected)
+	}
+
+	if succeeded := len(latencies); succeeded > 0 {
+		report.Recall = recallSum / float64(succeeded)
+		report.MRR = rrSum / float64(succeeded)
+	}
+	report.Latency = SummarizeLatencies(latencies)
+	if report.Errors == report.Queries && report.Queries > 0 {
+		return report, fmt.Errorf("all %d queries failed: %w", report.Queries, firstErr)
+	}
+	return report, nil
+}
@@ -0,0 +1,108 @@
+package evaluation
+
+import (
+	"context"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"math"
+	"testing"
+)
+
+func ids(values ...uint64) []muopdbclient.DocID {
+	docIDs := make([]muopdbclient.DocID, len(values))
+	for i, value := range values {
+		docIDs[i] = muopdbclient.NewDocIDFromUint64(value)
+	}
+	return docIDs
+}
+
+func TestRecall(t *testing.T) {
+	tests := []struct {
+		results, truth []muopdbclient.DocID
+		want           float64
+	}{
+		{results: ids(1, 2, 3), truth: ids(1, 2, 3), want: 1},
+		{results: ids(3, 2, 1), truth: ids(1, 2, 3), want: 1},
+		{results: ids(1, 9, 8, 7), truth: ids(1, 2), want: 0.5},
+		{results: ids(1, 1, 1), truth: ids(1, 2, 3, 4), want: 0.25},
+		{results: nil, truth: ids(1), want: 0},
+		{results: ids(1), truth: nil, want: 1},
+	}
+	for _, test := range tests {
+		if got := Recall(test.results, test.truth); got != test.want {
+			t.Errorf("Recall(%v, %v) = %v, want %v", test.results, test.truth, got, test.want)
+		}
+	}
+}
+
+func TestReciprocalRank(t *testing.T) {
+	tests := []struct {
+		results, truth []muopdbclient.DocID
+		want           float64
+	}{
+		{results: ids(1, 2, 3), truth: ids(1, 2), want: 1},
+		{results: ids(5, 6, 1), truth: ids(1, 5), want: 1.0 / 3},
+		{results: ids(5, 6), truth: ids(1), want: 0},
+		{results: ids(1), truth: nil, want: 0},
+	}
+	for _, test := range tests {
+		if got := ReciprocalRank(test.results, test.truth); got != test.want {
+			t.Errorf("ReciprocalRank(%v, %v) = %v, want %v", test.results, test.truth, got, test.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	server := muopdbtest.NewServer()
+	t.Cleanup(server.Close)
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := muopdbclient.NewClient(conn, muopdbclient.WithFlushPolicy(muopdbclient.FlushPolicy{}))
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	if err := client.CreateCollection(ctx, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	user := muopdbclient.NewDocIDFromUint64(0)
+	if _, err := client.Insert(ctx, muopdbclient.InsertRequest{
+		CollectionName: "docs",
+		DocIds:         ids(1, 2, 3),
+		Vectors:        []float32{0, 0, 1, 0, 5, 0},
+		UserIds:        []muopdbclient.DocID{user},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := SearchOptions{CollectionName: "docs", TopK: 2, UserIds: []muopdbclient.DocID{user}}
+	queries := [][]float32{{0, 0}, {5, 0}}
+	// The server returns 3 and 2 for the second query, missing 1 of its truth.
+	truth := [][]muopdbclient.DocID{ids(1, 2, 3), ids(1, 3)}
+	report, err := Evaluate(ctx, client, opts, queries, truth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Queries != 2 || report.Errors != 0 || report.K != 2 || report.Latency.Count != 2 {
+		t.Errorf("report = %+v, want 2 successful queries at k=2", report)
+	}
+	if math.Abs(report.Recall-0.75) > 1e-9 {
+		t.Errorf("recall = %v, want 0.75", report.Recall)
+	}
+	if math.Abs(report.MRR-0.5) > 1e-9 {
+		t.Errorf("MRR = %v, want 0.5", report.MRR)
+	}
+
+	if _, err := Evaluate(ctx, client, opts, queries, truth[:1]); err == nil {
+		t.Error("Evaluate accepted a ground truth shorter than the queries")
+	}
+	if _, err := Evaluate(ctx, client, SearchOptions{CollectionName: "missing", TopK: 2}, queries, truth); err == nil {
+		t.Error("Evaluate succeeded although every query failed")
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := Evaluate(canceled, client, opts, queries, truth); err != context.Canceled {
+		t.Errorf("Evaluate returned %v on a canceled context", err)
+	}
+}
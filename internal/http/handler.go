@@ -1 +1,129 @@
 package http
+
+import (
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type createCollectionResponse struct {
+	CollectionName string `json:"collection_name"`
+}
+
+type segmentsResponse struct {
+	CollectionName string   `json:"collection_name"`
+	SegmentNames   []string `json:"segment_names"`
+}
+
+type flushResponse struct {
+	CollectionName  string   `json:"collection_name"`
+	FlushedSegments []string `json:"flushed_segments"`
+}
+
+type compactRequest struct {
+	// SegmentNames are the segments to merge. All segments are compacted when
+	// empty.
+	SegmentNames []string `json:"segment_names"`
+}
+
+type compactResponse struct {
+	CollectionName    string   `json:"collection_name"`
+	CompactedSegments []string `json:"compacted_segments"`
+}
+
+func (app App) createCollection(w http.ResponseWriter, r *http.Request) {
+	var req muopdbclient.CollectionSpec
+	if err := readJSON(w, r, &req); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	builder, err := req.Builder()
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := app.muopDBClient.CreateCollectionFromBuilder(r.Context(), builder); err != nil {
+		muopDBErrorJSON(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createCollectionResponse{
+		CollectionName: builder.CollectionName,
+	})
+}
+
+func (app App) getSegments(w http.ResponseWriter, r *http.Request) {
+	collectionName := chi.URLParam(r, "collectionName")
+
+	response, err := app.muopDBClient.GetSegments(r.Context(), muopdbclient.GetSegmentsRequest{
+		CollectionName: collectionName,
+	})
+	if err != nil {
+		muopDBErrorJSON(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, segmentsResponse{
+		CollectionName: collectionName,
+		SegmentNames:   nonNil(response.SegmentNames),
+	})
+}
+
+func (app App) flushCollection(w http.ResponseWriter, r *http.Request) {
+	collectionName := chi.URLParam(r, "collectionName")
+
+	response, err := app.muopDBClient.Flush(r.Context(), muopdbclient.FlushRequest{
+		CollectionName: collectionName,
+	})
+	if err != nil {
+		muopDBErrorJSON(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flushResponse{
+		CollectionName:  collectionName,
+		FlushedSegments: nonNil(response.FlushedSegments),
+	})
+}
+
+func (app App) compactCollection(w http.ResponseWriter, r *http.Request) {
+	collectionName := chi.URLParam(r, "collectionName")
+
+	var req compactRequest
+	if err := readOptionalJSON(w, r, &req); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		response muopdbclient.CompactSegmentsResponse
+		err      error
+	)
+	if len(req.SegmentNames) == 0 {
+		response, err = app.muopDBClient.CompactAllSegments(r.Context(), collectionName)
+	} else {
+		response, err = app.muopDBClient.CompactSegments(r.Context(), muopdbclient.CompactSegmentsRequest{
+			CollectionName: collectionName,
+			SegmentNames:   req.SegmentNames,
+		})
+	}
+	if err != nil {
+		muopDBErrorJSON(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compactResponse{
+		CollectionName:    collectionName,
+		CompactedSegments: nonNil(response.CompactedSegments),
+	})
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
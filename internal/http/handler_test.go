@@ -0,0 +1,266 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/configs"
+	"github.com/TrungBui59/test_muopdb/internal/embedding"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestApp serves an App backed by a fake MuopDB server holding an empty
+// 4 dimensional collection named "docs".
+func newTestApp(t *testing.T, embedder embedding.Embedder) (App, muopdbclient.MuopDbClient) {
+	t.Helper()
+	server := muopdbtest.NewServer()
+	t.Cleanup(server.Close)
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := muopdbclient.NewClient(conn, muopdbclient.WithFlushPolicy(muopdbclient.FlushPolicy{}))
+	t.Cleanup(func() { client.Close() })
+
+	app := NewApp(configs.Config{}, client, embedder, nil)
+	if recorder := serve(app, http.MethodPost, "/collections", `{"collection_name": "docs", "num_features": 4}`); recorder.Code != http.StatusCreated {
+		t.Fatalf("creating the collection: status %d: %s", recorder.Code, recorder.Body)
+	}
+	return app, client
+}
+
+func serve(app App, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	app.routes().ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+// decode checks the status of a response and decodes its body into dst.
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, wantStatus int, dst any) {
+	t.Helper()
+	if recorder.Code != wantStatus {
+		t.Fatalf("status %d, want %d: %s", recorder.Code, wantStatus, recorder.Body)
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), dst); err != nil {
+		t.Fatalf("decoding %s: %v", recorder.Body, err)
+	}
+}
+
+func insert(t *testing.T, client muopdbclient.MuopDbClient, vectors map[uint64][]float32) {
+	t.Helper()
+	request := muopdbclient.InsertRequest{
+		CollectionName: "docs",
+		UserIds:        []muopdbclient.DocID{{}},
+	}
+	for id, vector := range vectors {
+		request.DocIds = append(request.DocIds, muopdbclient.NewDocIDFromUint64(id))
+		request.Vectors = append(request.Vectors, vector...)
+	}
+	if _, err := client.Insert(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateCollection(t *testing.T) {
+	app, _ := newTestApp(t, nil)
+
+	var response createCollectionResponse
+	decode(t, serve(app, http.MethodPost, "/collections", `{"collection_name": "other"}`), http.StatusCreated, &response)
+	if response.CollectionName != "other" {
+		t.Errorf("created collection %q, want other", response.CollectionName)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "duplicate", body: `{"collection_name": "docs"}`, wantStatus: http.StatusConflict},
+		{name: "invalid json", body: `{"collection_name": `, wantStatus: http.StatusBadRequest},
+		{name: "no body", body: ``, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", body: `{"collection_name": "new", "dimension": 4}`, wantStatus: http.StatusBadRequest},
+		{name: "trailing value", body: `{"collection_name": "new"} {}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid spec", body: `{"collection_name": "new", "quantization_type": "nope"}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var response errorResponse
+			decode(t, serve(app, http.MethodPost, "/collections", test.body), test.wantStatus, &response)
+			if response.Error == "" {
+				t.Error("the response carries no error")
+			}
+		})
+	}
+}
+
+func TestSegmentsFlushAndCompact(t *testing.T) {
+	app, client := newTestApp(t, nil)
+
+	segments := func() []string {
+		t.Helper()
+		var response segmentsResponse
+		decode(t, serve(app, http.MethodGet, "/collections/docs/segments", ""), http.StatusOK, &response)
+		if response.CollectionName != "docs" {
+			t.Errorf("segments of collection %q, want docs", response.CollectionName)
+		}
+		return response.SegmentNames
+	}
+	flush := func() []string {
+		t.Helper()
+		var response flushResponse
+		decode(t, serve(app, http.MethodPost, "/collections/docs/flush", ""), http.StatusOK, &response)
+		return response.FlushedSegments
+	}
+
+	if got := segments(); !reflect.DeepEqual(got, []string{}) {
+		t.Errorf("segments of a new collection = %v, want []", got)
+	}
+	if got := flush(); !reflect.DeepEqual(got, []string{}) {
+		t.Errorf("flushing nothing flushed %v, want []", got)
+	}
+
+	for i := uint64(0); i < 3; i++ {
+		insert(t, client, map[uint64][]float32{i: {float32(i), 0, 0, 0}})
+		if got, want := flush(), []string{fmt.Sprintf("segment_%d", i)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("flush %d flushed %v, want %v", i, got, want)
+		}
+	}
+	if got, want := segments(), []string{"segment_0", "segment_1", "segment_2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("segments = %v, want %v", got, want)
+	}
+
+	var compacted compactResponse
+	decode(t, serve(app, http.MethodPost, "/collections/docs/compact", `{"segment_names": ["segment_0", "segment_1"]}`),
+		http.StatusOK, &compacted)
+	if compacted.CollectionName != "docs" || compacted.CompactedSegments == nil {
+		t.Errorf("compacting named segments returned %+v", compacted)
+	}
+	if got, want := segments(), []string{"segment_2", "segment_3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("segments after compacting two of them = %v, want %v", got, want)
+	}
+
+	// Without a body every segment is compacted.
+	decode(t, serve(app, http.MethodPost, "/collections/docs/compact", ""), http.StatusOK, &compacted)
+	if got, want := segments(), []string{"segment_4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("segments after compacting all of them = %v, want %v", got, want)
+	}
+
+	var response errorResponse
+	decode(t, serve(app, http.MethodPost, "/collections/docs/compact", `{"segment_names": "segment_4"}`),
+		http.StatusBadRequest, &response)
+	decode(t, serve(app, http.MethodPost, "/collections/docs/compact", `{"segment_names": ["segment_0"]}`),
+		http.StatusNotFound, &response)
+}
+
+func TestMissingCollection(t *testing.T) {
+	app, _ := newTestApp(t, embedding.NewHashingEmbedder(4))
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodGet, path: "/collections/missing/segments"},
+		{method: http.MethodPost, path: "/collections/missing/flush"},
+		{method: http.MethodPost, path: "/collections/missing/compact"},
+		{method: http.MethodPost, path: "/collections/missing/compact", body: `{"segment_names": ["segment_0"]}`},
+		{method: http.MethodPost, path: "/collections/missing/search", body: `{"vector": [1, 0, 0, 0]}`},
+		{method: http.MethodPost, path: "/collections/missing/search", body: `{"query": "hello"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			var response errorResponse
+			decode(t, serve(app, test.method, test.path, test.body), http.StatusNotFound, &response)
+			if !strings.Contains(response.Error, "missing") {
+				t.Errorf("error %q does not name the collection", response.Error)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	embedder := embedding.NewHashingEmbedder(4)
+	app, client := newTestApp(t, embedder)
+
+	// The query is embedded to the vector of document 2, the others are far from it.
+	query, err := embedder.EmbedOne(context.Background(), "second document")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors := map[uint64][]float32{1: make([]float32, 4), 2: query, 3: make([]float32, 4)}
+	for i, value := range query {
+		vectors[1][i] = -value
+		vectors[3][i] = 2 * value
+	}
+	insert(t, client, vectors)
+
+	search := func(body string) searchResponse {
+		t.Helper()
+		var response searchResponse
+		decode(t, serve(app, http.MethodPost, "/collections/docs/search", body), http.StatusOK, &response)
+		return response
+	}
+
+	response := search(`{"query": "second document", "top_k": 2}`)
+	if len(response.Results) != 2 {
+		t.Fatalf("searching by query returned %d results, want 2", len(response.Results))
+	}
+	if top := response.Results[0]; top.ID != muopdbclient.NewDocIDFromUint64(2) || top.Score != 0 {
+		t.Errorf("the top result of the query is %+v, want document 2 at distance 0", top)
+	}
+
+	vector, err := json.Marshal(vectors[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = search(`{"vector": ` + string(vector) + `}`)
+	if len(response.Results) != 3 {
+		t.Fatalf("searching by vector returned %d results, want the 3 documents", len(response.Results))
+	}
+	if top := response.Results[0]; top.ID != muopdbclient.NewDocIDFromUint64(3) || top.Score != 0 {
+		t.Errorf("the top result of the vector is %+v, want document 3 at distance 0", top)
+	}
+
+	// Only the documents of the requested users are searched.
+	response = search(`{"vector": ` + string(vector) + `, "user_ids": ["7"]}`)
+	if len(response.Results) != 0 {
+		t.Errorf("searching another user returned %+v, want no results", response.Results)
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		noEmbedder bool
+		body       string
+		wantStatus int
+	}{
+		{name: "invalid json", body: `{"vector": [1, 0`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", body: `{"vector": [1, 0, 0, 0], "k": 3}`, wantStatus: http.StatusBadRequest},
+		{name: "no vector nor query", body: `{"top_k": 3}`, wantStatus: http.StatusBadRequest},
+		{name: "vector and query", body: `{"vector": [1, 0, 0, 0], "query": "hello"}`, wantStatus: http.StatusBadRequest},
+		{name: "wrong dimension", body: `{"vector": [1, 0]}`, wantStatus: http.StatusBadRequest},
+		{name: "query without embedder", noEmbedder: true, body: `{"query": "hello"}`, wantStatus: http.StatusNotImplemented},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var embedder embedding.Embedder = embedding.NewHashingEmbedder(4)
+			if test.noEmbedder {
+				embedder = nil
+			}
+			app, _ := newTestApp(t, embedder)
+
+			var response errorResponse
+			decode(t, serve(app, http.MethodPost, "/collections/docs/search", test.body), test.wantStatus, &response)
+			if response.Error == "" {
+				t.Error("the response carries no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http"
+)
+
+// maxBodyBytes bounds the size of request bodies.
+const maxBodyBytes = 32 << 20
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	if decoder.More() {
+		return errors.New("invalid request body: must only contain a single JSON value")
+	}
+	return nil
+}
+
+// readOptionalJSON is readJSON for endpoints whose body may be omitted.
+func readOptionalJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	err := readJSON(w, r, dst)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func errorJSON(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, errorResponse{Error: err.Error()})
+}
+
+// muopDBErrorJSON writes an error returned by the MuopDB client with the
+// closest HTTP status code.
+func muopDBErrorJSON(w http.ResponseWriter, err error) {
+	errorJSON(w, muopDBErrorStatus(err), err)
+}
+
+func muopDBErrorStatus(err error) int {
+	var (
+		dimensionErr  *muopdbclient.DimensionMismatchError
+		misalignedErr *muopdbclient.MisalignedVectorsError
+	)
+	if errors.As(err, &dimensionErr) || errors.As(err, &misalignedErr) ||
+		errors.Is(err, muopdbclient.ErrNoDocuments) || errors.Is(err, muopdbclient.ErrEmptyVector) {
+		return http.StatusBadRequest
+	}
+
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/docstore"
+	"github.com/TrungBui59/test_muopdb/internal/embedding"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+)
+
+const (
+	statusInserted = "inserted"
+	statusFailed   = "failed"
+)
+
+type ingestDocument struct {
+	// ID is required and must be unique within a request, a document without
+	// one would overwrite every other document without one.
+	ID   *muopdbclient.DocID `json:"id"`
+	Text string              `json:"text"`
+	// UserID defaults to the zero user.
+	UserID   muopdbclient.DocID `json:"user_id"`
+	Source   string             `json:"source,omitempty"`
+	Metadata map[string]string  `json:"metadata,omitempty"`
+}
+
+type ingestRequest struct {
+	Documents []ingestDocument `json:"documents"`
+}
+
+// documentStatus is the outcome of the document at the same position in the
+// request.
+type documentStatus struct {
+	ID     *muopdbclient.DocID `json:"id,omitempty"`
+	Status string              `json:"status"`
+	Error  string              `json:"error,omitempty"`
+}
+
+type ingestResponse struct {
+	NumInserted int              `json:"num_inserted"`
+	NumFailed   int              `json:"num_failed"`
+	Documents   []documentStatus `json:"documents"`
+}
+
+func (app App) ingestDocuments(w http.ResponseWriter, r *http.Request) {
+	collectionName := chi.URLParam(r, "collectionName")
+
+	var req ingestRequest
+	if err := readJSON(w, r, &req); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Documents) == 0 {
+		errorJSON(w, http.StatusBadRequest, errors.New("no documents to ingest"))
+		return
+	}
+	if app.embedder == nil {
+		errorJSON(w, http.StatusNotImplemented, errors.New("no embedding provider is configured"))
+		return
+	}
+
+	idCounts := make(map[muopdbclient.DocID]int, len(req.Documents))
+	for _, doc := range req.Documents {
+		if doc.ID != nil {
+			idCounts[*doc.ID]++
+		}
+	}
+
+	statuses := make([]documentStatus, len(req.Documents))
+	var (
+		texts   []string
+		indexes []int
+	)
+	for i, doc := range req.Documents {
+		statuses[i] = documentStatus{ID: doc.ID, Status: statusInserted}
+		switch {
+		case doc.ID == nil:
+			statuses[i].Error = "id is required"
+		case idCounts[*doc.ID] > 1:
+			statuses[i].Error = fmt.Sprintf("id %s appears %d times in the request", doc.ID, idCounts[*doc.ID])
+		case doc.Text == "":
+			statuses[i].Error = "text is empty"
+		}
+		if statuses[i].Error != "" {
+			statuses[i].Status = statusFailed
+			continue
+		}
+		texts = append(texts, doc.Text)
+		indexes = append(indexes, i)
+	}
+
+	if len(texts) > 0 {
+		vectors, err := embedding.Generate(r.Context(), app.embedder, texts, embedding.GenerateOptions{})
+		if err != nil {
+			errorJSON(w, http.StatusBadGateway, err)
+			return
+		}
+
+		// An insert applies to every user id it is given, so documents are
+		// inserted one user at a time.
+		var (
+			userOrder []muopdbclient.DocID
+			byUser    = make(map[muopdbclient.DocID][]int)
+		)
+		for i, docIndex := range indexes {
+			userID := req.Documents[docIndex].UserID
+			if _, ok := byUser[userID]; !ok {
+				userOrder = append(userOrder, userID)
+			}
+			byUser[userID] = append(byUser[userID], i)
+		}
+
+		for _, userID := range userOrder {
+			var (
+				docIds      []muopdbclient.DocID
+				flatVectors []float32
+			)
+			for _, i := range byUser[userID] {
+				docIds = append(docIds, *req.Documents[indexes[i]].ID)
+				flatVectors = append(flatVectors, vectors[i]...)
+			}
+
+			_, err := app.muopDBClient.Insert(r.Context(), muopdbclient.InsertRequest{
+				CollectionName: collectionName,
+				DocIds:         docIds,
+				Vectors:        flatVectors,
+				UserIds:        []muopdbclient.DocID{userID},
+			})
+			// The documents of a failed flush are inserted all the same.
+			var flushErr *muopdbclient.FlushError
+			if errors.As(err, &flushErr) {
+				log.Printf("Error flushing after ingesting: %v", err)
+				err = nil
+			}
+			if err == nil && app.documents != nil {
+				err = app.recordDocuments(collectionName, req.Documents, indexes, byUser[userID])
+			}
+			if err != nil {
+				for _, i := range byUser[userID] {
+					statuses[indexes[i]].Status = statusFailed
+					statuses[indexes[i]].Error = err.Error()
+				}
+			}
+		}
+	}
+
+	response := ingestResponse{Documents: statuses}
+	for _, status := range statuses {
+		if status.Status == statusInserted {
+			response.NumInserted++
+		} else {
+			response.NumFailed++
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// recordDocuments stores the ingested documents at documents[indexes[i]] for
+// every i in embedded.
+func (app App) recordDocuments(collectionName string, documents []ingestDocument, indexes, embedded []int) error {
+	docs := make([]docstore.Document, 0, len(embedded))
+	for _, i := range embedded {
+		doc := documents[indexes[i]]
+		docs = append(docs, docstore.Document{
+			ID:             *doc.ID,
+			Text:           doc.Text,
+			Source:         doc.Source,
+			Metadata:       doc.Metadata,
+			EmbeddingModel: app.embedder.ModelName(),
+		})
+	}
+
+	if err := app.documents.Put(collectionName, docs...); err != nil {
+		return fmt.Errorf("inserted but not recorded in the document store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/TrungBui59/test_muopdb/internal/configs"
+	"github.com/TrungBui59/test_muopdb/internal/embedding"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIngestDocumentsRequiresUniqueIDs(t *testing.T) {
+	server := muopdbtest.NewServer()
+	defer server.Close()
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := muopdbclient.NewClient(conn, muopdbclient.WithFlushPolicy(muopdbclient.FlushPolicy{}))
+	defer client.Close()
+	if err := client.CreateCollection(context.Background(), "docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp(configs.Config{}, client, embedding.NewHashingEmbedder(8), nil)
+	body := `{"documents": [
+		{"id": "1", "text": "first"},
+		{"text": "no id"},
+		{"id": "2", "text": "duplicate"},
+		{"id": "3", "text": "second"},
+		{"id": "2", "text": "duplicate again"}
+	]}`
+	recorder := httptest.NewRecorder()
+	app.routes().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/collections/docs/documents", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var response ingestResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.NumInserted != 2 || response.NumFailed != 3 {
+		t.Errorf("%d inserted and %d failed, want 2 and 3", response.NumInserted, response.NumFailed)
+	}
+	wantErrors := []string{
+		"",
+		"id is required",
+		"id 00000000-0000-0000-0000-000000000002 appears 2 times in the request",
+		"",
+		"id 00000000-0000-0000-0000-000000000002 appears 2 times in the request",
+	}
+	for i, status := range response.Documents {
+		if status.Error != wantErrors[i] {
+			t.Errorf("document %d failed with %q, want %q", i, status.Error, wantErrors[i])
+		}
+	}
+	if n := server.IndexServer.NumDocuments("docs"); n != 2 {
+		t.Errorf("the server holds %d documents, want 2", n)
+	}
+}
@@ -1,8 +1,9 @@
 package http
 
 import (
-	"github.com/TrungBui59/test_muopdb/interval/configs"
-	"github.com/TrungBui59/test_muopdb/interval/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/configs"
+	"github.com/TrungBui59/test_muopdb/internal/embedding"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
 	"github.com/go-chi/chi/v5/middleware"
 	"net/http"
 
@@ -13,6 +14,9 @@ import (
 type App struct {
 	muopDBClient muopdbclient.MuopDbClient
 	cfg          configs.Config
+	embedder     embedding.Embedder
+	// documents is optional, search results only carry their text when set.
+	documents DocumentStore
 }
 
 func (app App) routes() http.Handler {
@@ -30,5 +34,14 @@ func (app App) routes() http.Handler {
 	}))
 
 	mux.Use(middleware.Heartbeat("/ping"))
+
+	mux.Route("/collections", func(r chi.Router) {
+		r.Post("/", app.createCollection)
+		r.Get("/{collectionName}/segments", app.getSegments)
+		r.Post("/{collectionName}/flush", app.flushCollection)
+		r.Post("/{collectionName}/compact", app.compactCollection)
+		r.Post("/{collectionName}/search", app.search)
+		r.Post("/{collectionName}/documents", app.ingestDocuments)
+	})
 	return mux
 }
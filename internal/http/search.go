@@ -0,0 +1,127 @@
+package http
+
+import (
+	"errors"
+	"github.com/TrungBui59/test_muopdb/internal/docstore"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+)
+
+const (
+	defaultTopK           = 10
+	defaultEfConstruction = 100
+)
+
+// DocumentStore records ingested documents and maps search results back to them.
+type DocumentStore interface {
+	Put(collectionName string, docs ...docstore.Document) error
+	GetMany(collectionName string, ids []muopdbclient.DocID) (map[muopdbclient.DocID]docstore.Document, error)
+}
+
+// searchRequest takes either a raw vector or a text query to embed.
+type searchRequest struct {
+	Vector         []float32            `json:"vector,omitempty"`
+	Query          string               `json:"query,omitempty"`
+	TopK           uint32               `json:"top_k,omitempty"`
+	EfConstruction uint32               `json:"ef_construction,omitempty"`
+	RecordMetrics  bool                 `json:"record_metrics,omitempty"`
+	UserIds        []muopdbclient.DocID `json:"user_ids,omitempty"`
+}
+
+type searchResult struct {
+	ID       muopdbclient.DocID `json:"id"`
+	Score    float32            `json:"score"`
+	Text     string             `json:"text,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Metadata map[string]string  `json:"metadata,omitempty"`
+}
+
+type searchResponse struct {
+	Results          []searchResult `json:"results"`
+	NumPagesAccessed uint64         `json:"num_pages_accessed,omitempty"`
+}
+
+func (app App) search(w http.ResponseWriter, r *http.Request) {
+	collectionName := chi.URLParam(r, "collectionName")
+
+	var req searchRequest
+	if err := readJSON(w, r, &req); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if (len(req.Vector) == 0) == (req.Query == "") {
+		errorJSON(w, http.StatusBadRequest, errors.New("exactly one of vector and query must be set"))
+		return
+	}
+
+	vector := req.Vector
+	if req.Query != "" {
+		if app.embedder == nil {
+			errorJSON(w, http.StatusNotImplemented, errors.New("no embedding provider is configured"))
+			return
+		}
+
+		var err error
+		vector, err = app.embedder.EmbedOne(r.Context(), req.Query)
+		if err != nil {
+			errorJSON(w, http.StatusBadGateway, err)
+			return
+		}
+	}
+
+	if req.TopK == 0 {
+		req.TopK = defaultTopK
+	}
+	if req.EfConstruction == 0 {
+		req.EfConstruction = defaultEfConstruction
+	}
+	if len(req.UserIds) == 0 {
+		// Documents inserted without a user belong to the zero user.
+		req.UserIds = []muopdbclient.DocID{{}}
+	}
+
+	response, err := app.muopDBClient.Search(r.Context(), muopdbclient.SearchRequest{
+		CollectionName: collectionName,
+		Vector:         vector,
+		TopK:           req.TopK,
+		EfConstruction: req.EfConstruction,
+		RecordMetrics:  req.RecordMetrics,
+		UserIds:        req.UserIds,
+	})
+	if err != nil {
+		muopDBErrorJSON(w, err)
+		return
+	}
+
+	var docs map[muopdbclient.DocID]docstore.Document
+	if app.documents != nil {
+		docs, err = app.documents.GetMany(collectionName, response.DocIds)
+		if err != nil {
+			// Results are still useful without their text.
+			log.Printf("Error reading documents of collection %s: %v", collectionName, err)
+		}
+	}
+
+	results := make([]searchResult, len(response.DocIds))
+	for i, id := range response.DocIds {
+		results[i] = searchResult{
+			ID: id,
+		}
+		if i < len(response.Scores) {
+			results[i].Score = response.Scores[i]
+		}
+		if doc, ok := docs[id]; ok {
+			results[i].Text = doc.Text
+			results[i].Source = doc.Source
+			results[i].Metadata = doc.Metadata
+		}
+	}
+
+	writeJSON(w, http.StatusOK, searchResponse{
+		Results:          results,
+		NumPagesAccessed: response.NumPagesAccessed,
+	})
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/configs"
+	"github.com/TrungBui59/test_muopdb/internal/embedding"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultReadTimeout     = 10 * time.Second
+	defaultWriteTimeout    = 30 * time.Second
+	defaultIdleTimeout     = 120 * time.Second
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// NewApp builds the REST API. documents may be nil, ingested documents are then
+// not recorded and search results carry no text.
+func NewApp(cfg configs.Config, muopDBClient muopdbclient.MuopDbClient, embedder embedding.Embedder,
+	documents DocumentStore) App {
+	return App{
+		muopDBClient: muopDBClient,
+		cfg:          cfg,
+		embedder:     embedder,
+		documents:    documents,
+	}
+}
+
+// Serve listens on the configured address until ctx is done, then stops
+// accepting connections and waits for in-flight requests to finish.
+func (app App) Serve(ctx context.Context) error {
+	httpConfig := app.cfg.HttpConfig
+	srv := &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", httpConfig.Host, httpConfig.Port),
+		Handler:      app.routes(),
+		ReadTimeout:  secondsOr(httpConfig.ReadTimeoutSeconds, defaultReadTimeout),
+		WriteTimeout: secondsOr(httpConfig.WriteTimeoutSeconds, defaultWriteTimeout),
+		IdleTimeout:  secondsOr(httpConfig.IdleTimeoutSeconds, defaultIdleTimeout),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(),
+		secondsOr(httpConfig.ShutdownTimeoutSeconds, defaultShutdownTimeout))
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func secondsOr(seconds int, fallback time.Duration) time.Duration {
+	if seconds <= 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,350 @@
+package muopdbclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Row is a single document to insert.
+type Row struct {
+	ID     DocID
+	Vector []float32
+	UserID DocID
+}
+
+// RowIterator yields the rows to insert. Next returns io.EOF once all rows were
+// read.
+type RowIterator interface {
+	Next() (Row, error)
+}
+
+type sliceRowIterator struct {
+	rows []Row
+	next int
+}
+
+// NewSliceRowIterator iterates over rows already held in memory.
+func NewSliceRowIterator(rows []Row) RowIterator {
+	return &sliceRowIterator{rows: rows}
+}
+
+func (it *sliceRowIterator) Next() (Row, error) {
+	if it.next >= len(it.rows) {
+		return Row{}, io.EOF
+	}
+	row := it.rows[it.next]
+	it.next++
+	return row, nil
+}
+
+// BatchError is the error of a single failed batch.
+type BatchError struct {
+	Batch    int
+	FirstRow uint64
+	NumRows  int
+	Err      error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("batch %d (rows %d-%d): %v", e.Batch, e.FirstRow, e.FirstRow+uint64(e.NumRows)-1, e.Err)
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BulkInsertProgress is reported after every batch, successful or not. The
+// batch holds the rows numbered FirstRow to FirstRow+NumRows-1, from 0 in read
+// order. RowsDone counts the rows of every batch handled so far, including
+// failed ones. A batch failing with a *FlushError was inserted, and counts in
+// DocsInserted.
+type BulkInsertProgress struct {
+	Batch        int
+	FirstRow     uint64
+	NumRows      int
+	BatchErr     error
+	RowsDone     uint64
+	DocsInserted uint64
+	Elapsed      time.Duration
+}
+
+type BulkInsertSummary struct {
+	RowsRead      uint64
+	DocsInserted  uint64
+	Batches       int
+	FailedBatches []BatchError
+	Elapsed       time.Duration
+	DocsPerSecond float64
+}
+
+// BulkInserter batches rows by count and size and inserts the batches with a
+// bounded number of concurrent RPCs.
+type BulkInserter struct {
+	client         MuopDbClient
+	collectionName string
+	batchSize      int
+	maxBatchBytes  int
+	concurrency    int
+	packed         bool
+	continueOnErr  bool
+	onProgress     func(BulkInsertProgress)
+}
+
+type BulkOption func(*BulkInserter) error
+
+func NewBulkInserter(client MuopDbClient, collectionName string, opts ...BulkOption) (*BulkInserter, error) {
+	if collectionName == "" {
+		return nil, fmt.Errorf("collection name cannot be empty")
+	}
+	inserter := &BulkInserter{
+		client:         client,
+		collectionName: collectionName,
+		batchSize:      1000,
+		// Stay under the default 4MB gRPC message limit.
+		maxBatchBytes: 3 << 20,
+		concurrency:   4,
+	}
+	for _, opt := range opts {
+		if err := opt(inserter); err != nil {
+			return nil, err
+		}
+	}
+	return inserter, nil
+}
+
+func WithBatchSize(n int) BulkOption {
+	return func(b *BulkInserter) error {
+		if n <= 0 {
+			return fmt.Errorf("batch size must be > 0")
+		}
+		b.batchSize = n
+		return nil
+	}
+}
+
+func WithMaxBatchBytes(n int) BulkOption {
+	return func(b *BulkInserter) error {
+		if n <= 0 {
+			return fmt.Errorf("max batch bytes must be > 0")
+		}
+		b.maxBatchBytes = n
+		return nil
+	}
+}
+
+func WithConcurrency(n int) BulkOption {
+	return func(b *BulkInserter) error {
+		if n <= 0 {
+			return fmt.Errorf("concurrency must be > 0")
+		}
+		b.concurrency = n
+		return nil
+	}
+}
+
+// WithPackedInserts sends batches through InsertPacked instead of Insert.
+func WithPackedInserts() BulkOption {
+	return func(b *BulkInserter) error {
+		b.packed = true
+		return nil
+	}
+}
+
+// WithContinueOnError keeps inserting after a batch failed. Failed batches are
+// listed in the summary.
+func WithContinueOnError() BulkOption {
+	return func(b *BulkInserter) error {
+		b.continueOnErr = true
+		return nil
+	}
+}
+
+// WithProgressFunc registers a callback run after every batch. Calls are
+// serialized.
+func WithProgressFunc(fn func(BulkInsertProgress)) BulkOption {
+	return func(b *BulkInserter) error {
+		b.onProgress = fn
+		return nil
+	}
+}
+
+type rowBatch struct {
+	index    int
+	firstRow uint64
+	userID   DocID
+	docIds   []DocID
+	vectors  []float32
+}
+
+// rowOverheadBytes approximates the serialized size of a row besides its vector.
+const rowOverheadBytes = 16
+
+// Run reads every row and inserts them. Rows sharing a batch are inserted for
+// the same user, so a batch is cut whenever the user id changes; inputs grouped
+// by user batch best.
+func (b *BulkInserter) Run(ctx context.Context, rows RowIterator) (BulkInsertSummary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		start   = time.Now()
+		batches = make(chan rowBatch)
+		wg      sync.WaitGroup
+
+		mu           sync.Mutex
+		summary      BulkInsertSummary
+		firstErr     error
+		rowsDone     uint64
+		docsInserted uint64
+	)
+
+	for i := 0; i < b.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				numInserted, err := b.insert(ctx, batch)
+
+				mu.Lock()
+				rowsDone += uint64(len(batch.docIds))
+				docsInserted += uint64(numInserted)
+				if err != nil {
+					batchErr := BatchError{
+						Batch:    batch.index,
+						FirstRow: batch.firstRow,
+						NumRows:  len(batch.docIds),
+						Err:      err,
+					}
+					summary.FailedBatches = append(summary.FailedBatches, batchErr)
+					if !b.continueOnErr && firstErr == nil {
+						firstErr = batchErr
+						cancel()
+					}
+					err = batchErr
+				}
+				if b.onProgress != nil {
+					b.onProgress(BulkInsertProgress{
+						Batch:        batch.index,
+						FirstRow:     batch.firstRow,
+						NumRows:      len(batch.docIds),
+						BatchErr:     err,
+						RowsDone:     rowsDone,
+						DocsInserted: docsInserted,
+						Elapsed:      time.Since(start),
+					})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	readErr := b.produce(ctx, rows, batches, &summary)
+	close(batches)
+	wg.Wait()
+
+	summary.DocsInserted = docsInserted
+	summary.Elapsed = time.Since(start)
+	if seconds := summary.Elapsed.Seconds(); seconds > 0 {
+		summary.DocsPerSecond = float64(summary.DocsInserted) / seconds
+	}
+
+	if firstErr != nil {
+		return summary, firstErr
+	}
+	if readErr != nil {
+		return summary, readErr
+	}
+	if len(summary.FailedBatches) > 0 {
+		return summary, fmt.Errorf("%d of %d batches failed", len(summary.FailedBatches), summary.Batches)
+	}
+	return summary, nil
+}
+
+// produce splits the rows into batches and hands them to the workers.
+func (b *BulkInserter) produce(ctx context.Context, rows RowIterator, batches chan<- rowBatch, summary *BulkInsertSummary) error {
+	var (
+		current      *rowBatch
+		currentBytes int
+		dimension    int
+	)
+
+	send := func() error {
+		if current == nil {
+			return nil
+		}
+		select {
+		case batches <- *current:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		summary.Batches++
+		current = nil
+		currentBytes = 0
+		return nil
+	}
+
+	for {
+		row, err := rows.Next()
+		if err == io.EOF {
+			return send()
+		}
+		if err != nil {
+			return fmt.Errorf("reading row %d: %w", summary.RowsRead, err)
+		}
+
+		if dimension == 0 {
+			dimension = len(row.Vector)
+		}
+		if len(row.Vector) == 0 || len(row.Vector) != dimension {
+			return fmt.Errorf("row %d has %d features, expected %d", summary.RowsRead, len(row.Vector), dimension)
+		}
+
+		rowBytes := 4*len(row.Vector) + rowOverheadBytes
+		if current != nil && (current.userID != row.UserID ||
+			len(current.docIds) >= b.batchSize ||
+			currentBytes+rowBytes > b.maxBatchBytes) {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		if current == nil {
+			current = &rowBatch{
+				index:    summary.Batches,
+				firstRow: summary.RowsRead,
+				userID:   row.UserID,
+			}
+		}
+
+		current.docIds = append(current.docIds, row.ID)
+		current.vectors = append(current.vectors, row.Vector...)
+		currentBytes += rowBytes
+		summary.RowsRead++
+	}
+}
+
+func (b *BulkInserter) insert(ctx context.Context, batch rowBatch) (uint32, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if b.packed {
+		response, err := b.client.InsertPacked(ctx, InsertPackedRequest{
+			CollectionName: b.collectionName,
+			DocIds:         batch.docIds,
+			Vectors:        batch.vectors,
+			UserIds:        []DocID{batch.userID},
+		})
+		return response.NumDocsInserted, err
+	}
+
+	response, err := b.client.Insert(ctx, InsertRequest{
+		CollectionName: b.collectionName,
+		DocIds:         batch.docIds,
+		Vectors:        batch.vectors,
+		UserIds:        []DocID{batch.userID},
+	})
+	return response.NumDocsInserted, err
+}
@@ -0,0 +1,268 @@
+package muopdbclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// insertRecorder is a client recording the inserts it receives. Inserts of the
+// rows listed in failRows fail.
+type insertRecorder struct {
+	MuopDbClient
+
+	mu       sync.Mutex
+	inserts  []InsertRequest
+	packed   int
+	failRows map[uint64]bool
+}
+
+var errInsertFailed = errors.New("insert failed")
+
+func (r *insertRecorder) Insert(_ context.Context, request InsertRequest) (InsertResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inserts = append(r.inserts, request)
+	for _, id := range request.DocIds {
+		if r.failRows[id.Low] {
+			return InsertResponse{}, errInsertFailed
+		}
+	}
+	return InsertResponse{NumDocsInserted: uint32(len(request.DocIds))}, nil
+}
+
+func (r *insertRecorder) InsertPacked(ctx context.Context, request InsertPackedRequest) (InsertPackedResponse, error) {
+	response, err := r.Insert(ctx, InsertRequest(request))
+	r.mu.Lock()
+	r.packed++
+	r.mu.Unlock()
+	return InsertPackedResponse{NumDocsInserted: response.NumDocsInserted}, err
+}
+
+// batchSizes returns the number of documents of every insert, in the order of
+// their first doc id.
+func (r *insertRecorder) batchSizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inserts := slices.Clone(r.inserts)
+	sort.Slice(inserts, func(i, j int) bool { return inserts[i].DocIds[0].Low < inserts[j].DocIds[0].Low })
+	sizes := make([]int, len(inserts))
+	for i, insert := range inserts {
+		sizes[i] = len(insert.DocIds)
+	}
+	return sizes
+}
+
+func testRows(n int, dimension int, userOf func(i int) uint64) []Row {
+	rows := make([]Row, n)
+	for i := range rows {
+		vector := make([]float32, dimension)
+		vector[0] = float32(i)
+		rows[i] = Row{
+			ID:     NewDocIDFromUint64(uint64(i)),
+			Vector: vector,
+			UserID: NewDocIDFromUint64(userOf(i)),
+		}
+	}
+	return rows
+}
+
+func sameUser(int) uint64 { return 0 }
+
+func TestBulkInserterBatchSize(t *testing.T) {
+	client := &insertRecorder{}
+	inserter, err := NewBulkInserter(client, "docs", WithBatchSize(4), WithConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := inserter.Run(context.Background(), NewSliceRowIterator(testRows(10, 3, sameUser)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.RowsRead != 10 || summary.DocsInserted != 10 || summary.Batches != 3 {
+		t.Errorf("summary = %+v, want 10 rows inserted in 3 batches", summary)
+	}
+	if sizes := client.batchSizes(); !slices.Equal(sizes, []int{4, 4, 2}) {
+		t.Errorf("batch sizes = %v, want [4 4 2]", sizes)
+	}
+	if client.packed != 0 {
+		t.Errorf("%d packed inserts, want none", client.packed)
+	}
+}
+
+func TestBulkInserterMaxBatchBytes(t *testing.T) {
+	client := &insertRecorder{}
+	// A row of 4 features weighs 4*4+16 bytes, so 100 bytes hold 3 rows.
+	inserter, err := NewBulkInserter(client, "docs", WithMaxBatchBytes(100), WithPackedInserts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := inserter.Run(context.Background(), NewSliceRowIterator(testRows(7, 4, sameUser))); err != nil {
+		t.Fatal(err)
+	}
+	if sizes := client.batchSizes(); !slices.Equal(sizes, []int{3, 3, 1}) {
+		t.Errorf("batch sizes = %v, want [3 3 1]", sizes)
+	}
+	if client.packed != 3 {
+		t.Errorf("%d packed inserts, want 3", client.packed)
+	}
+}
+
+func TestBulkInserterCutsBatchesByUser(t *testing.T) {
+	client := &insertRecorder{}
+	inserter, err := NewBulkInserter(client, "docs", WithConcurrency(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := testRows(6, 2, func(i int) uint64 { return uint64(i / 2 % 2) })
+	if _, err := inserter.Run(context.Background(), NewSliceRowIterator(rows)); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.inserts) != 3 {
+		t.Fatalf("%d inserts, want one per run of the same user", len(client.inserts))
+	}
+	for i, insert := range client.inserts {
+		want := []DocID{NewDocIDFromUint64(uint64(i % 2))}
+		if !slices.Equal(insert.UserIds, want) {
+			t.Errorf("insert %d was for users %v, want %v", i, insert.UserIds, want)
+		}
+	}
+}
+
+func TestBulkInserterStopsOnError(t *testing.T) {
+	client := &insertRecorder{failRows: map[uint64]bool{0: true}}
+	inserter, err := NewBulkInserter(client, "docs", WithBatchSize(2), WithConcurrency(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := inserter.Run(context.Background(), NewSliceRowIterator(testRows(100, 2, sameUser)))
+	var batchErr BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, errInsertFailed) {
+		t.Fatalf("Run returned %v, want the BatchError of the first batch", err)
+	}
+	if batchErr.Batch != 0 || batchErr.FirstRow != 0 || batchErr.NumRows != 2 {
+		t.Errorf("batch error = %+v, want batch 0 with rows 0-1", batchErr)
+	}
+	if summary.RowsRead >= 100 {
+		t.Errorf("read all %d rows after the first batch failed", summary.RowsRead)
+	}
+}
+
+func TestBulkInserterContinueOnError(t *testing.T) {
+	client := &insertRecorder{failRows: map[uint64]bool{2: true, 7: true}}
+	var (
+		mu       sync.Mutex
+		progress []BulkInsertProgress
+	)
+	inserter, err := NewBulkInserter(client, "docs",
+		WithBatchSize(2),
+		WithContinueOnError(),
+		WithProgressFunc(func(p BulkInsertProgress) {
+			mu.Lock()
+			progress = append(progress, p)
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := inserter.Run(context.Background(), NewSliceRowIterator(testRows(10, 2, sameUser)))
+	if err == nil || err.Error() != "2 of 5 batches failed" {
+		t.Fatalf("Run returned %v, want 2 of 5 batches failed", err)
+	}
+	if summary.DocsInserted != 6 {
+		t.Errorf("inserted %d docs, want 6", summary.DocsInserted)
+	}
+
+	var failed []int
+	for _, batchErr := range summary.FailedBatches {
+		failed = append(failed, batchErr.Batch)
+	}
+	sort.Ints(failed)
+	if !slices.Equal(failed, []int{1, 3}) {
+		t.Errorf("failed batches = %v, want [1 3]", failed)
+	}
+
+	if len(progress) != 5 {
+		t.Fatalf("%d progress reports, want one per batch", len(progress))
+	}
+	if last := progress[len(progress)-1]; last.RowsDone != 10 || last.DocsInserted != 6 {
+		t.Errorf("last progress = %+v, want 10 rows done and 6 docs inserted", last)
+	}
+}
+
+type failingRows struct {
+	rows RowIterator
+	left int
+}
+
+func (f *failingRows) Next() (Row, error) {
+	if f.left == 0 {
+		return Row{}, fmt.Errorf("disk on fire")
+	}
+	f.left--
+	return f.rows.Next()
+}
+
+func TestBulkInserterReadErrors(t *testing.T) {
+	inserter, err := NewBulkInserter(&insertRecorder{}, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = inserter.Run(context.Background(), &failingRows{rows: NewSliceRowIterator(testRows(5, 2, sameUser)), left: 3})
+	if err == nil || err.Error() != "reading row 3: disk on fire" {
+		t.Errorf("Run returned %v, want the read error of row 3", err)
+	}
+
+	rows := testRows(3, 2, sameUser)
+	rows[2].Vector = []float32{1, 2, 3}
+	_, err = inserter.Run(context.Background(), NewSliceRowIterator(rows))
+	if err == nil || err.Error() != "row 2 has 3 features, expected 2" {
+		t.Errorf("Run returned %v, want a dimension error for row 2", err)
+	}
+}
+
+func TestBulkInserterOptions(t *testing.T) {
+	if _, err := NewBulkInserter(&insertRecorder{}, ""); err == nil {
+		t.Error("NewBulkInserter accepted an empty collection name")
+	}
+	for _, opt := range []BulkOption{WithBatchSize(0), WithMaxBatchBytes(-1), WithConcurrency(0)} {
+		if _, err := NewBulkInserter(&insertRecorder{}, "docs", opt); err == nil {
+			t.Error("NewBulkInserter accepted an invalid option")
+		}
+	}
+}
+
+func TestBulkInserterCountsDocsOfFailedFlushes(t *testing.T) {
+	_, client := newFlushFailingClient(t)
+	var inserted uint64
+	inserter, err := NewBulkInserter(client, "docs", WithBatchSize(2), WithContinueOnError(),
+		WithProgressFunc(func(progress BulkInsertProgress) {
+			var flushErr *FlushError
+			if !errors.As(progress.BatchErr, &flushErr) {
+				t.Errorf("batch %d failed with %v, want a FlushError", progress.Batch, progress.BatchErr)
+			}
+			inserted = progress.DocsInserted
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := inserter.Run(context.Background(), &sliceRowIterator{rows: testRows(5, 2, sameUser)})
+	if err == nil {
+		t.Error("Run hid the failed flushes")
+	}
+	if summary.DocsInserted != 5 || inserted != 5 {
+		t.Errorf("counted %d then %d documents inserted, want the 5 inserted before their flushes failed", inserted, summary.DocsInserted)
+	}
+}
@@ -3,43 +3,77 @@ package muopdbclient
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	pb "github.com/TrungBui59/test_muopdb/api/pb"
 	"google.golang.org/grpc"
-	"reflect"
+	"math"
 )
 
 type muopDBClient struct {
 	conn             *grpc.ClientConn
 	indexClient      pb.IndexServerClient
 	aggregatorClient pb.AggregatorClient
+	flusher          *flusher
+	schemas          *collectionSchemas
 }
 
 func (m muopDBClient) InsertPacked(ctx context.Context, request InsertPackedRequest) (InsertPackedResponse, error) {
-	rpcRequest := pb.InsertRequest{
-		CollectionName: request.CollectionName,
-	}
-
-	response, err := m.indexClient.Insert(ctx, &rpcRequest)
+	err := m.schemas.validateInsert(request.CollectionName, len(request.DocIds), request.Vectors)
 	if err != nil {
 		return InsertPackedResponse{}, err
 	}
 
-	_, err = m.Flush(ctx, FlushRequest{
+	lowDocIds, highDocIds := splitDocIDs(request.DocIds)
+	lowUserIds, highUserIds := splitDocIDs(request.UserIds)
+
+	rpcRequest := pb.InsertPackedRequest{
 		CollectionName: request.CollectionName,
-	})
+		LowIds:         packUint64s(lowDocIds),
+		HighIds:        packUint64s(highDocIds),
+		Vectors:        packFloat32s(request.Vectors),
+		LowUserIds:     lowUserIds,
+		HighUserIds:    highUserIds,
+	}
 
+	response, err := m.indexClient.InsertPacked(ctx, &rpcRequest)
 	if err != nil {
 		return InsertPackedResponse{}, err
 	}
 
+	// The documents are inserted even when the flush fails, so the response
+	// goes along with the *FlushError.
+	err = m.flusher.inserted(ctx, request.CollectionName, response.NumDocsInserted)
 	return InsertPackedResponse{
 		NumDocsInserted: response.NumDocsInserted,
-	}, nil
+	}, err
+}
+
+// packUint64s packs the values with 8 little-endian bytes per number, the layout
+// InsertPackedRequest expects for its ids.
+func packUint64s(values []uint64) []byte {
+	packed := make([]byte, 8*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint64(packed[i*8:], value)
+	}
+	return packed
 }
 
+// packFloat32s packs the vectors with 4 little-endian bytes per number, which is
+// how the server reinterprets InsertPackedRequest.vectors as f32.
+func packFloat32s(values []float32) []byte {
+	packed := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(packed[i*4:], math.Float32bits(value))
+	}
+	return packed
+}
+
+// Close flushes pending documents according to the flush policy and closes the
+// underlying connection.
 func (m muopDBClient) Close() error {
-	return m.conn.Close()
+	flushErr := m.flusher.close(context.Background())
+	return errors.Join(flushErr, m.conn.Close())
 }
 
 func (m muopDBClient) CreateCollection(ctx context.Context, collectionName string) error {
@@ -51,9 +85,71 @@ func (m muopDBClient) CreateCollection(ctx context.Context, collectionName strin
 	return err
 }
 
+// CreateCollectionFromBuilder creates a collection with every option set on the
+// builder. Options that were not set are left for the server to default.
+func (m muopDBClient) CreateCollectionFromBuilder(ctx context.Context, builder *CollectionBuilder) error {
+	if builder == nil {
+		return fmt.Errorf("collection builder cannot be nil")
+	}
+
+	request := pb.CreateCollectionRequest{
+		CollectionName:                            builder.CollectionName,
+		NumFeatures:                               builder.NumFeatures,
+		CentroidsMaxNeighbors:                     builder.CentroidsMaxNeighbors,
+		CentroidsMaxLayers:                        builder.CentroidsMaxLayers,
+		CentroidsEfConstruction:                   builder.CentroidsEfConstruction,
+		CentroidsBuilderVectorStorageMemorySize:   builder.CentroidsBuilderVectorStorageMemorySize,
+		CentroidsBuilderVectorStorageFileSize:     builder.CentroidsBuilderVectorStorageFileSize,
+		ProductQuantizationMaxIteration:           builder.ProductQuantizationMaxIteration,
+		ProductQuantizationBatchSize:              builder.ProductQuantizationBatchSize,
+		ProductQuantizationSubvectorDimension:     builder.ProductQuantizationSubvectorDimension,
+		ProductQuantizationNumBits:                builder.ProductQuantizationNumBits,
+		ProductQuantizationNumTrainingRows:        builder.ProductQuantizationNumTrainingRows,
+		InitialNumCentroids:                       builder.InitialNumCentroids,
+		NumDataPointsForClustering:                builder.NumDataPointsForClustering,
+		MaxClustersPerVector:                      builder.MaxClustersPerVector,
+		ClusteringDistanceThresholdPct:            builder.ClusteringDistanceThresholdPct,
+		PostingListBuilderVectorStorageMemorySize: builder.PostingListBuilderVectorStorageMemorySize,
+		PostingListBuilderVectorStorageFileSize:   builder.PostingListBuilderVectorStorageFileSize,
+		MaxPostingListSize:                        builder.MaxPostingListSize,
+		PostingListKmeansUnbalancedPenalty:        builder.PostingListKmeansUnbalancedPenalty,
+		Reindex:                                   builder.Reindex,
+		WalFileSize:                               builder.WalFileSize,
+		MaxPendingOps:                             builder.MaxPendingOps,
+		MaxTimeToFlushMs:                          builder.MaxTimeToFlushMs,
+	}
+
+	if builder.QuantizationType != nil {
+		quantizationType := pb.QuantizerType(*builder.QuantizationType)
+		request.QuantizationType = &quantizationType
+	}
+	if builder.PostingListEncodingType != nil {
+		encodingType := pb.IntSeqEncodingType(*builder.PostingListEncodingType)
+		request.PostingListEncodingType = &encodingType
+	}
+
+	_, err := m.indexClient.CreateCollection(ctx, &request)
+	if err != nil {
+		return err
+	}
+	m.schemas.register(builder)
+	return nil
+}
+
+// RegisterCollection tells the client the schema of an existing collection so
+// requests against it are validated locally.
+func (m muopDBClient) RegisterCollection(builder *CollectionBuilder) {
+	m.schemas.register(builder)
+}
+
 func (m muopDBClient) Insert(ctx context.Context, request InsertRequest) (InsertResponse, error) {
-	lowDocIds, highDocIds := splitIDs(paddingIds(request.DocIds))
-	lowUserIds, highUserIds := splitIDs(paddingIds(request.UserIds))
+	err := m.schemas.validateInsert(request.CollectionName, len(request.DocIds), request.Vectors)
+	if err != nil {
+		return InsertResponse{}, err
+	}
+
+	lowDocIds, highDocIds := splitDocIDs(request.DocIds)
+	lowUserIds, highUserIds := splitDocIDs(request.UserIds)
 
 	rpcRequest := pb.InsertRequest{
 		CollectionName: request.CollectionName,
@@ -69,55 +165,20 @@ func (m muopDBClient) Insert(ctx context.Context, request InsertRequest) (Insert
 		return InsertResponse{}, err
 	}
 
-	_, err = m.Flush(ctx, FlushRequest{
-		CollectionName: request.CollectionName,
-	})
-	if err != nil {
-		return InsertResponse{}, err
-	}
-
+	// The documents are inserted even when the flush fails, so the response
+	// goes along with the *FlushError.
+	err = m.flusher.inserted(ctx, request.CollectionName, response.NumDocsInserted)
 	return InsertResponse{
 		NumDocsInserted: response.NumDocsInserted,
-	}, nil
-}
-
-func splitIDs(ids [][]byte) ([]uint64, []uint64) {
-	var lowIds []uint64
-	var highIds []uint64
-	for i := 0; i < len(ids); i++ {
-		low := binary.LittleEndian.Uint64(ids[i][:8])
-		high := binary.LittleEndian.Uint64(ids[i][8:])
-		lowIds = append(lowIds, low)
-		highIds = append(highIds, high)
-	}
-	return lowIds, highIds
-}
-
-func paddingIds(ids [][]byte) [][]byte {
-	paddedIds := make([][]byte, len(ids))
-	for i := 0; i < len(ids); i++ {
-		paddedIds[i] = make([]byte, 16) // create a 16-byte slice
-		copy(paddedIds[i], ids[i])      // copy original data
-	}
-	return paddedIds
+	}, err
 }
 
-func mergeIds(lowIds, highIds []uint64) [][]byte {
-	var ids [][]byte
-	for i := 0; i < len(lowIds); i++ {
-		lowBytes := make([]byte, 8)
-		highBytes := make([]byte, 8)
-		binary.LittleEndian.PutUint64(lowBytes, lowIds[i])
-		binary.LittleEndian.PutUint64(highBytes, highIds[i])
-		id := append(lowBytes, highBytes...)
-		ids = append(ids, id)
+func (m muopDBClient) Search(ctx context.Context, request SearchRequest) (SearchResponse, error) {
+	if err := m.schemas.validateSearch(request.CollectionName, request.Vector); err != nil {
+		return SearchResponse{}, err
 	}
-	return ids
-}
 
-func (m muopDBClient) Search(ctx context.Context, request SearchRequest) (SearchResponse, error) {
-	paddedUserIds := paddingIds(request.UserIds)
-	lowUserIDs, highUserIDs := splitIDs(paddedUserIds)
+	lowUserIDs, highUserIDs := splitDocIDs(request.UserIds)
 	grpcRequest := pb.SearchRequest{
 		CollectionName: request.CollectionName,
 		Vector:         request.Vector,
@@ -134,12 +195,37 @@ func (m muopDBClient) Search(ctx context.Context, request SearchRequest) (Search
 	}
 
 	return SearchResponse{
-		DocIds:           mergeIds(response.LowIds, response.HighIds),
+		DocIds:           mergeDocIDs(response.LowIds, response.HighIds),
 		Scores:           response.Scores,
 		NumPagesAccessed: response.NumPagesAccessed,
 	}, nil
 }
 
+// Get searches through the Aggregator service, which fans the query out to the
+// index servers backing the given index.
+func (m muopDBClient) Get(ctx context.Context, request GetRequest) (GetResponse, error) {
+	lowUserIDs, highUserIDs := splitDocIDs(request.UserIds)
+	grpcRequest := pb.GetRequest{
+		Index:          request.Index,
+		Vector:         request.Vector,
+		TopK:           request.TopK,
+		EfConstruction: request.EfConstruction,
+		RecordMetrics:  request.RecordMetrics,
+		LowUserIds:     lowUserIDs,
+		HighUserIds:    highUserIDs,
+	}
+
+	response, err := m.aggregatorClient.Get(ctx, &grpcRequest)
+	if err != nil {
+		return GetResponse{}, err
+	}
+
+	return GetResponse{
+		DocIds:           mergeDocIDs(response.LowIds, response.HighIds),
+		NumPagesAccessed: response.NumPagesAccessed,
+	}, nil
+}
+
 func (m muopDBClient) Flush(ctx context.Context, request FlushRequest) (FlushResponse, error) {
 	rpcRequest := pb.FlushRequest{
 		CollectionName: request.CollectionName,
@@ -149,38 +235,113 @@ func (m muopDBClient) Flush(ctx context.Context, request FlushRequest) (FlushRes
 	if err != nil {
 		return FlushResponse{}, err
 	}
+	m.flusher.flushed(request.CollectionName)
 	return FlushResponse{
 		FlushedSegments: response.FlushedSegments,
 	}, nil
 }
 
+func (m muopDBClient) GetSegments(ctx context.Context, request GetSegmentsRequest) (GetSegmentsResponse, error) {
+	rpcRequest := pb.GetSegmentsRequest{
+		CollectionName: request.CollectionName,
+	}
+
+	response, err := m.indexClient.GetSegments(ctx, &rpcRequest)
+	if err != nil {
+		return GetSegmentsResponse{}, err
+	}
+	return GetSegmentsResponse{
+		SegmentNames: response.SegmentNames,
+	}, nil
+}
+
+func (m muopDBClient) CompactSegments(ctx context.Context, request CompactSegmentsRequest) (CompactSegmentsResponse, error) {
+	if len(request.SegmentNames) == 0 {
+		return CompactSegmentsResponse{}, fmt.Errorf("no segments to compact in collection %q", request.CollectionName)
+	}
+
+	rpcRequest := pb.CompactSegmentsRequest{
+		CollectionName: request.CollectionName,
+		SegmentNames:   request.SegmentNames,
+	}
+
+	_, err := m.indexClient.CompactSegments(ctx, &rpcRequest)
+	if err != nil {
+		return CompactSegmentsResponse{}, err
+	}
+	return CompactSegmentsResponse{
+		CompactedSegments: request.SegmentNames,
+	}, nil
+}
+
+// CompactAllSegments lists the segments of a collection and compacts all of them
+// into one. It is a no-op when the collection has fewer than two segments.
+func (m muopDBClient) CompactAllSegments(ctx context.Context, collectionName string) (CompactSegmentsResponse, error) {
+	segments, err := m.GetSegments(ctx, GetSegmentsRequest{
+		CollectionName: collectionName,
+	})
+	if err != nil {
+		return CompactSegmentsResponse{}, err
+	}
+
+	if len(segments.SegmentNames) < 2 {
+		return CompactSegmentsResponse{}, nil
+	}
+
+	return m.CompactSegments(ctx, CompactSegmentsRequest{
+		CollectionName: collectionName,
+		SegmentNames:   segments.SegmentNames,
+	})
+}
+
 type MuopDbClient interface {
 	CreateCollection(ctx context.Context, collectionName string) error
+	CreateCollectionFromBuilder(ctx context.Context, builder *CollectionBuilder) error
+	RegisterCollection(builder *CollectionBuilder)
 	Insert(ctx context.Context, request InsertRequest) (InsertResponse, error)
 	InsertPacked(ctx context.Context, request InsertPackedRequest) (InsertPackedResponse, error)
 	Search(ctx context.Context, request SearchRequest) (SearchResponse, error)
+	Get(ctx context.Context, request GetRequest) (GetResponse, error)
 	Flush(ctx context.Context, request FlushRequest) (FlushResponse, error)
+	GetSegments(ctx context.Context, request GetSegmentsRequest) (GetSegmentsResponse, error)
+	CompactSegments(ctx context.Context, request CompactSegmentsRequest) (CompactSegmentsResponse, error)
+	CompactAllSegments(ctx context.Context, collectionName string) (CompactSegmentsResponse, error)
 	Close() error
 }
 
-func NewClient(conn *grpc.ClientConn) MuopDbClient {
-	return &muopDBClient{
-		conn:             conn,
-		indexClient:      pb.NewIndexServerClient(conn),
-		aggregatorClient: pb.NewAggregatorClient(conn),
-	}
+type clientOptions struct {
+	flushPolicy FlushPolicy
 }
 
-func main() {
-	ids := make([][]byte, 100)
-	for i := range ids {
-		ids[i] = make([]byte, 16)
-		binary.LittleEndian.PutUint64(ids[i], uint64(i))
+type ClientOption func(*clientOptions)
+
+// WithFlushPolicy sets when the client flushes collections it inserted into.
+// Defaults to DefaultFlushPolicy.
+func WithFlushPolicy(policy FlushPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.flushPolicy = policy
 	}
+}
 
-	lowIds, highIds := splitIDs(ids)
-	mergedIds := mergeIds(lowIds, highIds)
+func NewClient(conn *grpc.ClientConn, opts ...ClientOption) MuopDbClient {
+	options := clientOptions{
+		flushPolicy: DefaultFlushPolicy,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
 
-	areEqual := reflect.DeepEqual(ids, mergedIds)
-	fmt.Println(areEqual)
+	client := &muopDBClient{
+		conn:             conn,
+		indexClient:      pb.NewIndexServerClient(conn),
+		aggregatorClient: pb.NewAggregatorClient(conn),
+		schemas:          newCollectionSchemas(),
+	}
+	client.flusher = newFlusher(options.flushPolicy, func(ctx context.Context, collectionName string) error {
+		_, err := client.Flush(ctx, FlushRequest{
+			CollectionName: collectionName,
+		})
+		return err
+	})
+	return client
 }
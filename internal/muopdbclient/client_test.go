@@ -0,0 +1,34 @@
+package muopdbclient
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestPackUint64s(t *testing.T) {
+	packed := packUint64s([]uint64{1, 0x0102030405060708, math.MaxUint64})
+	want := []byte{
+		1, 0, 0, 0, 0, 0, 0, 0,
+		8, 7, 6, 5, 4, 3, 2, 1,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("packUint64s = %x, want %x", packed, want)
+	}
+	if packed := packUint64s(nil); len(packed) != 0 {
+		t.Errorf("packUint64s(nil) = %x, want no bytes", packed)
+	}
+}
+
+func TestPackFloat32s(t *testing.T) {
+	packed := packFloat32s([]float32{1, -2.5, 0})
+	want := []byte{
+		0x00, 0x00, 0x80, 0x3f,
+		0x00, 0x00, 0x20, 0xc0,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("packFloat32s = %x, want %x", packed, want)
+	}
+}
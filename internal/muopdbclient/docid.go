@@ -0,0 +1,138 @@
+package muopdbclient
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DocID is a 128-bit id as used by MuopDB for both doc ids and user ids.
+// Protobuf has no uint128 type, so the server receives it as two 64-bit halves.
+type DocID struct {
+	Low  uint64
+	High uint64
+}
+
+// NewDocIDFromUint64 returns the id whose lower 64 bits are v.
+func NewDocIDFromUint64(v uint64) DocID {
+	return DocID{Low: v}
+}
+
+// NewDocIDFromUUID parses a UUID string, with or without hyphens. The first
+// 8 bytes of the UUID become the high half of the id.
+func NewDocIDFromUUID(uuid string) (DocID, error) {
+	raw := strings.ReplaceAll(uuid, "-", "")
+	if len(raw) != 32 {
+		return DocID{}, fmt.Errorf("invalid uuid %q: expected 32 hex digits", uuid)
+	}
+	decoded, err := hex.DecodeString(raw)
+	if err != nil {
+		return DocID{}, fmt.Errorf("invalid uuid %q: %w", uuid, err)
+	}
+	return DocID{
+		Low:  binary.BigEndian.Uint64(decoded[8:]),
+		High: binary.BigEndian.Uint64(decoded[:8]),
+	}, nil
+}
+
+// NewDocIDFromBytes reads an id from at most 16 little-endian bytes, low half
+// first. Shorter inputs are zero padded.
+func NewDocIDFromBytes(b []byte) (DocID, error) {
+	if len(b) > 16 {
+		return DocID{}, fmt.Errorf("id is %d bytes long, at most 16 are allowed", len(b))
+	}
+	padded := make([]byte, 16)
+	copy(padded, b)
+	return DocID{
+		Low:  binary.LittleEndian.Uint64(padded[:8]),
+		High: binary.LittleEndian.Uint64(padded[8:]),
+	}, nil
+}
+
+// ParseDocID accepts either the output of DocID.String, a UUID without hyphens
+// or a decimal uint64. A 32 character input is always read as a UUID, even
+// when it is all digits.
+func ParseDocID(s string) (DocID, error) {
+	if len(s) != 32 {
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return NewDocIDFromUint64(v), nil
+		}
+	}
+	return NewDocIDFromUUID(s)
+}
+
+// Bytes returns the 16 little-endian bytes of the id, low half first.
+func (id DocID) Bytes() []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b[:8], id.Low)
+	binary.LittleEndian.PutUint64(b[8:], id.High)
+	return b
+}
+
+// String formats the id as a canonical UUID.
+func (id DocID) String() string {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], id.High)
+	binary.BigEndian.PutUint64(b[8:], id.Low)
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// MarshalText encodes the id as its String form, so ids read naturally in JSON.
+func (id DocID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *DocID) UnmarshalText(text []byte) error {
+	parsed, err := ParseDocID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// UnmarshalJSON accepts a string, in any form ParseDocID accepts, or a JSON
+// number, as the ids written by Python and notebook tools usually are.
+func (id *DocID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return id.UnmarshalText([]byte(s))
+	}
+	v, err := strconv.ParseUint(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %s: expected a string or a non-negative integer", data)
+	}
+	*id = NewDocIDFromUint64(v)
+	return nil
+}
+
+func splitDocIDs(ids []DocID) ([]uint64, []uint64) {
+	lowIds := make([]uint64, len(ids))
+	highIds := make([]uint64, len(ids))
+	for i, id := range ids {
+		lowIds[i] = id.Low
+		highIds[i] = id.High
+	}
+	return lowIds, highIds
+}
+
+func mergeDocIDs(lowIds, highIds []uint64) []DocID {
+	ids := make([]DocID, len(lowIds))
+	for i := range lowIds {
+		ids[i].Low = lowIds[i]
+		if i < len(highIds) {
+			ids[i].High = highIds[i]
+		}
+	}
+	return ids
+}
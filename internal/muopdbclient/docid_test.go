@@ -0,0 +1,128 @@
+package muopdbclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestDocIDStringRoundTrip(t *testing.T) {
+	ids := []DocID{
+		{},
+		NewDocIDFromUint64(42),
+		{Low: 0x10},
+		{Low: 0x0123456789abcdef, High: 0xfedcba9876543210},
+		{Low: ^uint64(0), High: ^uint64(0)},
+	}
+	for _, id := range ids {
+		parsed, err := ParseDocID(id.String())
+		if err != nil {
+			t.Fatalf("ParseDocID(%q): %v", id.String(), err)
+		}
+		if parsed != id {
+			t.Errorf("ParseDocID(%q) = %+v, want %+v", id.String(), parsed, id)
+		}
+	}
+}
+
+func TestParseDocID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  DocID
+	}{
+		{input: "0", want: DocID{}},
+		{input: "42", want: DocID{Low: 42}},
+		{input: "18446744073709551615", want: DocID{Low: ^uint64(0)}},
+		{input: "00000000-0000-0000-0000-000000000010", want: DocID{Low: 0x10}},
+		{input: "00000000000000000000000000000010", want: DocID{Low: 0x10}},
+		{input: "fedcba98765432100123456789abcdef", want: DocID{Low: 0x0123456789abcdef, High: 0xfedcba9876543210}},
+		{input: "FEDCBA98-7654-3210-0123-456789ABCDEF", want: DocID{Low: 0x0123456789abcdef, High: 0xfedcba9876543210}},
+	}
+	for _, test := range tests {
+		got, err := ParseDocID(test.input)
+		if err != nil {
+			t.Errorf("ParseDocID(%q): %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseDocID(%q) = %+v, want %+v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseDocIDErrors(t *testing.T) {
+	for _, input := range []string{"", "-1", "abc", "18446744073709551616", "0000000000000000000000000000001g"} {
+		if _, err := ParseDocID(input); err == nil {
+			t.Errorf("ParseDocID(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestDocIDBytesRoundTrip(t *testing.T) {
+	id := DocID{Low: 0x0123456789abcdef, High: 0xfedcba9876543210}
+	b := id.Bytes()
+	if want := []byte{0xef, 0xcd, 0xab, 0x89, 0x67, 0x45, 0x23, 0x01, 0x10, 0x32, 0x54, 0x76, 0x98, 0xba, 0xdc, 0xfe}; !bytes.Equal(b, want) {
+		t.Fatalf("Bytes() = %x, want %x", b, want)
+	}
+	parsed, err := NewDocIDFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != id {
+		t.Errorf("NewDocIDFromBytes(%x) = %+v, want %+v", b, parsed, id)
+	}
+
+	short, err := NewDocIDFromBytes([]byte{7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short != NewDocIDFromUint64(7) {
+		t.Errorf("NewDocIDFromBytes([7]) = %+v, want the id 7", short)
+	}
+	if _, err := NewDocIDFromBytes(make([]byte, 17)); err == nil {
+		t.Error("NewDocIDFromBytes accepted 17 bytes")
+	}
+}
+
+func TestDocIDTextRoundTrip(t *testing.T) {
+	id := DocID{Low: 5, High: 9}
+	text, err := id.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed DocID
+	if err := parsed.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if parsed != id {
+		t.Errorf("UnmarshalText(%q) = %+v, want %+v", text, parsed, id)
+	}
+}
+
+func TestDocIDUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  DocID
+	}{
+		{input: `42`, want: DocID{Low: 42}},
+		{input: `"42"`, want: DocID{Low: 42}},
+		{input: `"00000000-0000-0007-0000-00000000000b"`, want: DocID{Low: 11, High: 7}},
+	}
+	for _, test := range tests {
+		var id DocID
+		if err := json.Unmarshal([]byte(test.input), &id); err != nil {
+			t.Errorf("unmarshaling %s: %v", test.input, err)
+			continue
+		}
+		if id != test.want {
+			t.Errorf("unmarshaling %s = %+v, want %+v", test.input, id, test.want)
+		}
+	}
+
+	for _, input := range []string{`-1`, `4.2`, `1e3`, `18446744073709551616`, `true`, `"x"`} {
+		var id DocID
+		if err := json.Unmarshal([]byte(input), &id); err == nil {
+			t.Errorf("unmarshaling %s succeeded, want an error", input)
+		}
+	}
+}
@@ -1,9 +1,8 @@
 package muopdbclient
 
-type Id struct {
-	LowIds  []uint64
-	HighIds []uint64
-}
+import (
+	"fmt"
+)
 
 type SearchRequest struct {
 	CollectionName string
@@ -11,24 +10,38 @@ type SearchRequest struct {
 	TopK           uint32
 	EfConstruction uint32
 	RecordMetrics  bool
-	UserIds        []Id
+	UserIds        []DocID
 }
 
 type SearchResponse struct {
-	DocIds           [][]byte
+	DocIds           []DocID
 	Scores           []float32
 	NumPagesAccessed uint64
 }
 
+type GetRequest struct {
+	Index          string
+	Vector         []float32
+	TopK           uint32
+	EfConstruction uint32
+	RecordMetrics  bool
+	UserIds        []DocID
+}
+
+type GetResponse struct {
+	DocIds           []DocID
+	NumPagesAccessed uint64
+}
+
 type InsertRequest struct {
 	CollectionName string
-	DocIds         []Id
+	DocIds         []DocID
 	Vectors        []float32
-	UserIds        []Id
+	UserIds        []DocID
 }
 
 type InsertResponse struct {
-	DocIds []Id
+	NumDocsInserted uint32
 }
 
 type FlushRequest struct {
@@ -39,23 +52,34 @@ type FlushResponse struct {
 	FlushedSegments []string
 }
 
+type GetSegmentsRequest struct {
+	CollectionName string
+}
+
+type GetSegmentsResponse struct {
+	SegmentNames []string
+}
+
+type CompactSegmentsRequest struct {
+	CollectionName string
+	SegmentNames   []string
+}
+
+type CompactSegmentsResponse struct {
+	CompactedSegments []string
+}
+
 type InsertPackedRequest struct {
 	CollectionName string
-	DocIds         []Id
-	Vectors []float32
-	UserIds []Id
+	DocIds         []DocID
+	Vectors        []float32
+	UserIds        []DocID
 }
 
 type InsertPackedResponse struct {
 	NumDocsInserted uint32
 }
 
-package collection
-
-import (
-	"fmt"
-)
-
 // Enums from the proto.
 type QuantizerType int32
 
@@ -73,32 +97,32 @@ const (
 
 // CollectionBuilder holds all configuration parameters for a collection.
 type CollectionBuilder struct {
-	CollectionName                                string
-	NumFeatures                                   *uint32
-	CentroidsMaxNeighbors                         *uint32
-	CentroidsMaxLayers                            *uint32
-	CentroidsEfConstruction                       *uint32
-	CentroidsBuilderVectorStorageMemorySize       *uint64
-	CentroidsBuilderVectorStorageFileSize         *uint64
-	QuantizationType                              *QuantizerType
-	ProductQuantizationMaxIteration               *uint32
-	ProductQuantizationBatchSize                  *uint32
-	ProductQuantizationSubvectorDimension         *uint32
-	ProductQuantizationNumBits                    *uint32
-	ProductQuantizationNumTrainingRows            *uint32
-	InitialNumCentroids                           *uint32
-	NumDataPointsForClustering                    *uint32
-	MaxClustersPerVector                          *uint32
-	ClusteringDistanceThresholdPct                *float32
-	PostingListEncodingType                       *IntSeqEncodingType
-	PostingListBuilderVectorStorageMemorySize       *uint64
-	PostingListBuilderVectorStorageFileSize         *uint64
-	MaxPostingListSize                            *uint64
-	PostingListKmeansUnbalancedPenalty            *float32
-	Reindex                                       *bool
-	WalFileSize                                   *uint64
-	MaxPendingOps                                 *uint64
-	MaxTimeToFlushMs                              *uint64
+	CollectionName                            string
+	NumFeatures                               *uint32
+	CentroidsMaxNeighbors                     *uint32
+	CentroidsMaxLayers                        *uint32
+	CentroidsEfConstruction                   *uint32
+	CentroidsBuilderVectorStorageMemorySize   *uint64
+	CentroidsBuilderVectorStorageFileSize     *uint64
+	QuantizationType                          *QuantizerType
+	ProductQuantizationMaxIteration           *uint32
+	ProductQuantizationBatchSize              *uint32
+	ProductQuantizationSubvectorDimension     *uint32
+	ProductQuantizationNumBits                *uint32
+	ProductQuantizationNumTrainingRows        *uint32
+	InitialNumCentroids                       *uint32
+	NumDataPointsForClustering                *uint32
+	MaxClustersPerVector                      *uint32
+	ClusteringDistanceThresholdPct            *float32
+	PostingListEncodingType                   *IntSeqEncodingType
+	PostingListBuilderVectorStorageMemorySize *uint64
+	PostingListBuilderVectorStorageFileSize   *uint64
+	MaxPostingListSize                        *uint64
+	PostingListKmeansUnbalancedPenalty        *float32
+	Reindex                                   *bool
+	WalFileSize                               *uint64
+	MaxPendingOps                             *uint64
+	MaxTimeToFlushMs                          *uint64
 }
 
 type Option func(*CollectionBuilder) error
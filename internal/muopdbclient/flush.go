@@ -0,0 +1,149 @@
+package muopdbclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// FlushPolicy decides when the client flushes a collection after inserting into
+// it. The zero value never flushes, leaving it to the server's own
+// max_time_to_flush_ms. Conditions can be combined.
+type FlushPolicy struct {
+	// EveryCall flushes after every Insert and InsertPacked call.
+	EveryCall bool
+	// EveryNDocs flushes a collection once this many documents were inserted
+	// into it since its last flush.
+	EveryNDocs uint32
+	// Interval flushes, in the background, every collection that received
+	// documents since its last flush.
+	Interval time.Duration
+	// OnClose flushes every collection with unflushed documents on Close.
+	OnClose bool
+}
+
+// DefaultFlushPolicy only flushes pending documents when the client is closed.
+var DefaultFlushPolicy = FlushPolicy{OnClose: true}
+
+// FlushError is returned by Insert and InsertPacked when the server inserted
+// the documents but the flush the FlushPolicy asked for failed. The response
+// returned with it counts the documents inserted, which must not be inserted
+// again.
+type FlushError struct {
+	CollectionName string
+	Err            error
+}
+
+func (e *FlushError) Error() string {
+	return fmt.Sprintf("documents inserted into collection %q but flushing it failed: %v", e.CollectionName, e.Err)
+}
+
+func (e *FlushError) Unwrap() error {
+	return e.Err
+}
+
+// flusher tracks the documents inserted since the last flush of each collection
+// and applies the FlushPolicy.
+type flusher struct {
+	policy FlushPolicy
+	flush  func(ctx context.Context, collectionName string) error
+
+	mu      sync.Mutex
+	pending map[string]uint32
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newFlusher(policy FlushPolicy, flush func(ctx context.Context, collectionName string) error) *flusher {
+	f := &flusher{
+		policy:  policy,
+		flush:   flush,
+		pending: make(map[string]uint32),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if policy.Interval > 0 {
+		go f.run()
+	} else {
+		close(f.done)
+	}
+	return f
+}
+
+func (f *flusher) run() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			if err := f.flushPending(context.Background()); err != nil {
+				log.Printf("Error flushing collections: %v", err)
+			}
+		}
+	}
+}
+
+// inserted records numDocs new documents in the collection and flushes it if the
+// policy asks for it. A failed flush is returned as a *FlushError.
+func (f *flusher) inserted(ctx context.Context, collectionName string, numDocs uint32) error {
+	f.mu.Lock()
+	f.pending[collectionName] += numDocs
+	shouldFlush := f.policy.EveryCall ||
+		(f.policy.EveryNDocs > 0 && f.pending[collectionName] >= f.policy.EveryNDocs)
+	f.mu.Unlock()
+
+	if !shouldFlush {
+		return nil
+	}
+	if err := f.flush(ctx, collectionName); err != nil {
+		return &FlushError{CollectionName: collectionName, Err: err}
+	}
+	return nil
+}
+
+// flushed marks the collection as having nothing pending.
+func (f *flusher) flushed(collectionName string) {
+	f.mu.Lock()
+	delete(f.pending, collectionName)
+	f.mu.Unlock()
+}
+
+func (f *flusher) flushPending(ctx context.Context) error {
+	f.mu.Lock()
+	collections := make([]string, 0, len(f.pending))
+	for collectionName := range f.pending {
+		collections = append(collections, collectionName)
+	}
+	f.mu.Unlock()
+
+	var errs []error
+	for _, collectionName := range collections {
+		if err := f.flush(ctx, collectionName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// close stops the background flushes and, if the policy asks for it, flushes
+// every collection with pending documents.
+func (f *flusher) close(ctx context.Context) error {
+	f.stopOnce.Do(func() { close(f.stop) })
+	<-f.done
+
+	if !f.policy.OnClose {
+		return nil
+	}
+	return f.flushPending(ctx)
+}
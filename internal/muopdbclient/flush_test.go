@@ -0,0 +1,186 @@
+package muopdbclient
+
+import (
+	"context"
+	"errors"
+	pb "github.com/TrungBui59/test_muopdb/api/pb"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flushRecorder records the collections flushed by a flusher.
+type flushRecorder struct {
+	mu      sync.Mutex
+	flushed []string
+	err     error
+	f       *flusher
+}
+
+func newRecordedFlusher(policy FlushPolicy) *flushRecorder {
+	r := &flushRecorder{}
+	r.f = newFlusher(policy, func(ctx context.Context, collectionName string) error {
+		r.mu.Lock()
+		r.flushed = append(r.flushed, collectionName)
+		r.mu.Unlock()
+		if r.err != nil {
+			return r.err
+		}
+		r.f.flushed(collectionName)
+		return nil
+	})
+	return r
+}
+
+func (r *flushRecorder) calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.flushed)
+}
+
+func TestFlushPolicyNever(t *testing.T) {
+	r := newRecordedFlusher(FlushPolicy{})
+	for range 3 {
+		if err := r.f.inserted(context.Background(), "docs", 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.f.close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := r.calls(); len(calls) != 0 {
+		t.Errorf("flushed %v, want no flush", calls)
+	}
+}
+
+func TestFlushPolicyEveryCall(t *testing.T) {
+	r := newRecordedFlusher(FlushPolicy{EveryCall: true})
+	r.f.inserted(context.Background(), "docs", 1)
+	r.f.inserted(context.Background(), "other", 1)
+	r.f.close(context.Background())
+
+	if calls, want := r.calls(), []string{"docs", "other"}; !slices.Equal(calls, want) {
+		t.Errorf("flushed %v, want %v", calls, want)
+	}
+}
+
+func TestFlushPolicyEveryNDocs(t *testing.T) {
+	r := newRecordedFlusher(FlushPolicy{EveryNDocs: 10})
+	for _, numDocs := range []uint32{4, 4, 4, 4, 4, 2} {
+		if err := r.f.inserted(context.Background(), "docs", numDocs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.f.inserted(context.Background(), "other", 9)
+
+	// The third call reaches 12 documents, the count then restarts and the
+	// sixth call reaches 10 again.
+	if calls, want := r.calls(), []string{"docs", "docs"}; !slices.Equal(calls, want) {
+		t.Errorf("flushed %v, want %v", calls, want)
+	}
+}
+
+func TestFlushPolicyInterval(t *testing.T) {
+	r := newRecordedFlusher(FlushPolicy{Interval: 5 * time.Millisecond})
+	r.f.inserted(context.Background(), "docs", 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.calls()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the interval never flushed the collection")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := r.f.close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once flushed, nothing is pending and later ticks flush nothing.
+	if calls := r.calls(); !slices.Equal(calls, []string{"docs"}) {
+		t.Errorf("flushed %v, want a single flush of docs", calls)
+	}
+}
+
+func TestFlushPolicyOnClose(t *testing.T) {
+	r := newRecordedFlusher(DefaultFlushPolicy)
+	r.f.inserted(context.Background(), "docs", 1)
+	r.f.inserted(context.Background(), "docs", 1)
+	if calls := r.calls(); len(calls) != 0 {
+		t.Fatalf("flushed %v before close", calls)
+	}
+
+	if err := r.f.close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := r.calls(); !slices.Equal(calls, []string{"docs"}) {
+		t.Errorf("flushed %v on close, want docs once", calls)
+	}
+}
+
+func TestFlushPolicyErrors(t *testing.T) {
+	r := newRecordedFlusher(FlushPolicy{EveryCall: true, OnClose: true})
+	r.err = errors.New("unavailable")
+
+	var flushErr *FlushError
+	if err := r.f.inserted(context.Background(), "docs", 1); !errors.As(err, &flushErr) || !errors.Is(err, r.err) {
+		t.Errorf("inserted returned %v, want a FlushError wrapping %v", err, r.err)
+	}
+	// The failed flush leaves the documents pending for close.
+	if err := r.f.close(context.Background()); !errors.Is(err, r.err) {
+		t.Errorf("close returned %v, want %v", err, r.err)
+	}
+}
+
+// newFlushFailingClient returns a client flushing after every insert into a
+// server failing every flush.
+func newFlushFailingClient(t *testing.T) (*muopdbtest.Server, MuopDbClient) {
+	t.Helper()
+	server := muopdbtest.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == pb.IndexServer_Flush_FullMethodName {
+			return nil, status.Error(codes.Unavailable, "flush failed")
+		}
+		return handler(ctx, req)
+	}))
+	t.Cleanup(server.Close)
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(conn, WithFlushPolicy(FlushPolicy{EveryCall: true}))
+	t.Cleanup(func() { client.Close() })
+	if err := client.CreateCollection(context.Background(), "docs"); err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+func TestInsertReportsDocsOfFailedFlush(t *testing.T) {
+	server, client := newFlushFailingClient(t)
+	ids := []DocID{NewDocIDFromUint64(1), NewDocIDFromUint64(2)}
+
+	response, err := client.Insert(context.Background(), InsertRequest{
+		CollectionName: "docs", DocIds: ids, Vectors: []float32{1, 2}, UserIds: []DocID{{}},
+	})
+	var flushErr *FlushError
+	if !errors.As(err, &flushErr) || flushErr.CollectionName != "docs" || status.Code(flushErr.Err) != codes.Unavailable {
+		t.Errorf("Insert returned %v, want a FlushError of docs", err)
+	}
+	if response.NumDocsInserted != 2 {
+		t.Errorf("Insert counted %d documents, want the 2 inserted before the flush failed", response.NumDocsInserted)
+	}
+
+	packed, err := client.InsertPacked(context.Background(), InsertPackedRequest{
+		CollectionName: "docs", DocIds: []DocID{NewDocIDFromUint64(3)}, Vectors: []float32{3}, UserIds: []DocID{{}},
+	})
+	if !errors.As(err, &flushErr) || packed.NumDocsInserted != 1 {
+		t.Errorf("InsertPacked returned %+v, %v, want 1 document and a FlushError", packed, err)
+	}
+	if n := server.IndexServer.NumDocuments("docs"); n != 3 {
+		t.Errorf("the server holds %d documents, want 3", n)
+	}
+}
@@ -0,0 +1,155 @@
+package muopdbclient
+
+import (
+	"context"
+	pb "github.com/TrungBui59/test_muopdb/api/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures how failed unary calls are retried.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt. Values below 2 disable retries.
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// Jitter randomizes each backoff by up to this fraction, e.g. 0.2 for ±20%.
+	Jitter float64
+	// BudgetMaxTokens and BudgetTokenRatio bound retries across calls: every
+	// failed attempt costs a token, every success gives back BudgetTokenRatio
+	// tokens, and retries stop while fewer than half of the tokens are left.
+	// A zero BudgetMaxTokens disables the budget.
+	BudgetMaxTokens  float64
+	BudgetTokenRatio float64
+	// RetryInserts also retries Insert and InsertPacked. Only enable it when
+	// every insert carries caller-chosen doc ids, so that an insert applied
+	// before its response was lost is rewritten rather than duplicated.
+	RetryInserts bool
+}
+
+// retryableCodes are the status codes for which the server did not (or could not
+// have) applied the call.
+var retryableCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+}
+
+// isRetryableMethod reports whether the call can safely be sent again. Inserts
+// are only retried when the caller opted in with retryInserts.
+func isRetryableMethod(method string, retryInserts bool) bool {
+	switch method {
+	case pb.IndexServer_Search_FullMethodName,
+		pb.IndexServer_Flush_FullMethodName,
+		pb.IndexServer_GetSegments_FullMethodName,
+		pb.Aggregator_Get_FullMethodName:
+		return true
+	case pb.IndexServer_Insert_FullMethodName,
+		pb.IndexServer_InsertPacked_FullMethodName:
+		return retryInserts
+	default:
+		return false
+	}
+}
+
+// RetryDialOptions returns the dial options installing the retry interceptor.
+// It returns no options when the policy does not allow retries.
+func RetryDialOptions(policy RetryPolicy) []grpc.DialOption {
+	if policy.MaxAttempts < 2 {
+		return nil
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(newRetryInterceptor(policy)),
+	}
+}
+
+func newRetryInterceptor(policy RetryPolicy) grpc.UnaryClientInterceptor {
+	budget := newRetryBudget(policy.BudgetMaxTokens, policy.BudgetTokenRatio)
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		for attempt := 1; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil {
+				budget.success()
+				return nil
+			}
+
+			if !retryableCodes[status.Code(err)] || !isRetryableMethod(method, policy.RetryInserts) {
+				return err
+			}
+			budget.failure()
+			if attempt >= policy.MaxAttempts || !budget.allowRetry() {
+				return err
+			}
+
+			timer := time.NewTimer(policy.backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// backoff returns how long to wait after the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(backoff)
+}
+
+// retryBudget is the token bucket of gRPC's retry throttling.
+type retryBudget struct {
+	mu         sync.Mutex
+	maxTokens  float64
+	tokenRatio float64
+	tokens     float64
+}
+
+func newRetryBudget(maxTokens, tokenRatio float64) *retryBudget {
+	return &retryBudget{
+		maxTokens:  maxTokens,
+		tokenRatio: tokenRatio,
+		tokens:     maxTokens,
+	}
+}
+
+func (b *retryBudget) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.tokenRatio, b.maxTokens)
+}
+
+func (b *retryBudget) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = max(b.tokens-1, 0)
+}
+
+func (b *retryBudget) allowRetry() bool {
+	if b.maxTokens <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens > b.maxTokens/2
+}
@@ -0,0 +1,190 @@
+package muopdbclient
+
+import (
+	"context"
+	pb "github.com/TrungBui59/test_muopdb/api/pb"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsRetryableMethod(t *testing.T) {
+	tests := []struct {
+		method       string
+		retryInserts bool
+		want         bool
+	}{
+		{method: pb.IndexServer_Search_FullMethodName, want: true},
+		{method: pb.IndexServer_Flush_FullMethodName, want: true},
+		{method: pb.IndexServer_GetSegments_FullMethodName, want: true},
+		{method: pb.Aggregator_Get_FullMethodName, want: true},
+		{method: pb.IndexServer_Insert_FullMethodName, want: false},
+		{method: pb.IndexServer_InsertPacked_FullMethodName, want: false},
+		{method: pb.IndexServer_Insert_FullMethodName, retryInserts: true, want: true},
+		{method: pb.IndexServer_InsertPacked_FullMethodName, retryInserts: true, want: true},
+		{method: pb.IndexServer_CreateCollection_FullMethodName, retryInserts: true, want: false},
+		{method: pb.IndexServer_CompactSegments_FullMethodName, retryInserts: true, want: false},
+	}
+	for _, test := range tests {
+		if got := isRetryableMethod(test.method, test.retryInserts); got != test.want {
+			t.Errorf("isRetryableMethod(%s, %v) = %v, want %v", test.method, test.retryInserts, got, test.want)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	budget := newRetryBudget(4, 0.5)
+	if !budget.allowRetry() {
+		t.Fatal("a full budget does not allow retries")
+	}
+
+	// Two failures leave 2 tokens, which is not more than half of 4.
+	budget.failure()
+	if !budget.allowRetry() {
+		t.Error("3 tokens left do not allow retries")
+	}
+	budget.failure()
+	if budget.allowRetry() {
+		t.Error("2 tokens left still allow retries")
+	}
+
+	budget.success()
+	if !budget.allowRetry() {
+		t.Error("a success did not give back enough tokens to retry")
+	}
+
+	// Tokens never go below zero nor above the maximum.
+	for range 10 {
+		budget.failure()
+	}
+	if budget.tokens != 0 {
+		t.Errorf("%v tokens after many failures, want 0", budget.tokens)
+	}
+	for range 100 {
+		budget.success()
+	}
+	if budget.tokens != 4 {
+		t.Errorf("%v tokens after many successes, want 4", budget.tokens)
+	}
+}
+
+func TestRetryBudgetDisabled(t *testing.T) {
+	budget := newRetryBudget(0, 0)
+	for range 10 {
+		budget.failure()
+	}
+	if !budget.allowRetry() {
+		t.Error("a disabled budget stopped retries")
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff:    10 * time.Millisecond,
+		MaxBackoff:        50 * time.Millisecond,
+		BackoffMultiplier: 2,
+	}
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := policy.backoff(attempt + 1); got != want*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", attempt+1, got, want*time.Millisecond)
+		}
+	}
+
+	policy.Jitter = 0.2
+	for range 100 {
+		if got := policy.backoff(1); got < 8*time.Millisecond || got > 12*time.Millisecond {
+			t.Fatalf("backoff(1) with 20%% jitter = %v, want within 8ms-12ms", got)
+		}
+	}
+}
+
+// unavailableFor fails the first n calls of every method with Unavailable.
+func unavailableFor(n int32, calls *atomic.Int32) grpc.ServerOption {
+	return grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if calls.Add(1) <= n {
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+		return handler(ctx, req)
+	})
+}
+
+func newRetryingClient(t *testing.T, server *muopdbtest.Server, policy RetryPolicy) MuopDbClient {
+	t.Helper()
+	conn, err := server.Dial(RetryDialOptions(policy)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(conn, WithFlushPolicy(FlushPolicy{}))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	insert := InsertRequest{
+		CollectionName: "docs",
+		DocIds:         []DocID{NewDocIDFromUint64(1)},
+		Vectors:        []float32{1, 2},
+		UserIds:        []DocID{{}},
+	}
+
+	tests := []struct {
+		name         string
+		failures     int32
+		retryInserts bool
+		wantCode     codes.Code
+		wantCalls    int32
+	}{
+		{name: "retried until it succeeds", failures: 2, retryInserts: true, wantCode: codes.OK, wantCalls: 3},
+		{name: "gives up after MaxAttempts", failures: 3, retryInserts: true, wantCode: codes.Unavailable, wantCalls: 3},
+		{name: "inserts are not retried by default", failures: 1, wantCode: codes.Unavailable, wantCalls: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := muopdbtest.NewServer(unavailableFor(test.failures, &calls))
+			defer server.Close()
+			if _, err := server.IndexServer.CreateCollection(context.Background(), &pb.CreateCollectionRequest{CollectionName: "docs"}); err != nil {
+				t.Fatal(err)
+			}
+
+			policy.RetryInserts = test.retryInserts
+			client := newRetryingClient(t, server, policy)
+
+			_, err := client.Insert(context.Background(), insert)
+			if code := status.Code(err); code != test.wantCode {
+				t.Errorf("Insert returned %v, want code %v", err, test.wantCode)
+			}
+			if got := calls.Load(); got != test.wantCalls {
+				t.Errorf("the server received %d calls, want %d", got, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryInterceptorRetriesSearch(t *testing.T) {
+	var calls atomic.Int32
+	server := muopdbtest.NewServer(unavailableFor(1, &calls))
+	defer server.Close()
+	if _, err := server.IndexServer.CreateCollection(context.Background(), &pb.CreateCollectionRequest{CollectionName: "docs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newRetryingClient(t, server, RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	_, err := client.Search(context.Background(), SearchRequest{
+		CollectionName: "docs",
+		Vector:         []float32{1, 2},
+		TopK:           1,
+		UserIds:        []DocID{{}},
+	})
+	if err != nil {
+		t.Fatalf("Search was not retried: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("the server received %d calls, want 2", got)
+	}
+}
@@ -0,0 +1,135 @@
+package muopdbclient
+
+import (
+	"fmt"
+)
+
+// CollectionSpec mirrors the CollectionBuilder options under the names the REST
+// API and the sweep specs use. Omitted fields keep the server defaults.
+type CollectionSpec struct {
+	CollectionName                            string   `json:"collection_name"`
+	NumFeatures                               *uint32  `json:"num_features,omitempty"`
+	CentroidsMaxNeighbors                     *uint32  `json:"centroids_max_neighbors,omitempty"`
+	CentroidsMaxLayers                        *uint32  `json:"centroids_max_layers,omitempty"`
+	CentroidsEfConstruction                   *uint32  `json:"centroids_ef_construction,omitempty"`
+	CentroidsBuilderVectorStorageMemorySize   *uint64  `json:"centroids_builder_vector_storage_memory_size,omitempty"`
+	CentroidsBuilderVectorStorageFileSize     *uint64  `json:"centroids_builder_vector_storage_file_size,omitempty"`
+	QuantizationType                          *string  `json:"quantization_type,omitempty"`
+	ProductQuantizationMaxIteration           *uint32  `json:"product_quantization_max_iteration,omitempty"`
+	ProductQuantizationBatchSize              *uint32  `json:"product_quantization_batch_size,omitempty"`
+	ProductQuantizationSubvectorDimension     *uint32  `json:"product_quantization_subvector_dimension,omitempty"`
+	ProductQuantizationNumBits                *uint32  `json:"product_quantization_num_bits,omitempty"`
+	ProductQuantizationNumTrainingRows        *uint32  `json:"product_quantization_num_training_rows,omitempty"`
+	InitialNumCentroids                       *uint32  `json:"initial_num_centroids,omitempty"`
+	NumDataPointsForClustering                *uint32  `json:"num_data_points_for_clustering,omitempty"`
+	MaxClustersPerVector                      *uint32  `json:"max_clusters_per_vector,omitempty"`
+	ClusteringDistanceThresholdPct            *float32 `json:"clustering_distance_threshold_pct,omitempty"`
+	PostingListEncodingType                   *string  `json:"posting_list_encoding_type,omitempty"`
+	PostingListBuilderVectorStorageMemorySize *uint64  `json:"posting_list_builder_vector_storage_memory_size,omitempty"`
+	PostingListBuilderVectorStorageFileSize   *uint64  `json:"posting_list_builder_vector_storage_file_size,omitempty"`
+	MaxPostingListSize                        *uint64  `json:"max_posting_list_size,omitempty"`
+	PostingListKmeansUnbalancedPenalty        *float32 `json:"posting_list_kmeans_unbalanced_penalty,omitempty"`
+	Reindex                                   *bool    `json:"reindex,omitempty"`
+	WalFileSize                               *uint64  `json:"wal_file_size,omitempty"`
+	MaxPendingOps                             *uint64  `json:"max_pending_ops,omitempty"`
+	MaxTimeToFlushMs                          *uint64  `json:"max_time_to_flush_ms,omitempty"`
+}
+
+// QuantizerTypes and IntSeqEncodingTypes name the enums as the proto does.
+var QuantizerTypes = map[string]QuantizerType{
+	"NO_QUANTIZER":      NoQuantizer,
+	"PRODUCT_QUANTIZER": ProductQuantizer,
+}
+
+var IntSeqEncodingTypes = map[string]IntSeqEncodingType{
+	"PLAIN_ENCODING": PlainEncoding,
+	"ELIAS_FANO":     EliasFano,
+}
+
+func (spec CollectionSpec) Builder() (*CollectionBuilder, error) {
+	var opts []Option
+	if spec.NumFeatures != nil {
+		opts = append(opts, WithNumFeatures(*spec.NumFeatures))
+	}
+	if spec.CentroidsMaxNeighbors != nil {
+		opts = append(opts, WithCentroidsMaxNeighbors(*spec.CentroidsMaxNeighbors))
+	}
+	if spec.CentroidsMaxLayers != nil {
+		opts = append(opts, WithCentroidsMaxLayers(*spec.CentroidsMaxLayers))
+	}
+	if spec.CentroidsEfConstruction != nil {
+		opts = append(opts, WithCentroidsEfConstruction(*spec.CentroidsEfConstruction))
+	}
+	if spec.CentroidsBuilderVectorStorageMemorySize != nil {
+		opts = append(opts, WithCentroidsBuilderVectorStorageMemorySize(*spec.CentroidsBuilderVectorStorageMemorySize))
+	}
+	if spec.CentroidsBuilderVectorStorageFileSize != nil {
+		opts = append(opts, WithCentroidsBuilderVectorStorageFileSize(*spec.CentroidsBuilderVectorStorageFileSize))
+	}
+	if spec.QuantizationType != nil {
+		quantizationType, ok := QuantizerTypes[*spec.QuantizationType]
+		if !ok {
+			return nil, fmt.Errorf("unknown quantization type %q", *spec.QuantizationType)
+		}
+		opts = append(opts, WithQuantizationType(quantizationType))
+	}
+	if spec.ProductQuantizationMaxIteration != nil {
+		opts = append(opts, WithProductQuantizationMaxIteration(*spec.ProductQuantizationMaxIteration))
+	}
+	if spec.ProductQuantizationBatchSize != nil {
+		opts = append(opts, WithProductQuantizationBatchSize(*spec.ProductQuantizationBatchSize))
+	}
+	if spec.ProductQuantizationSubvectorDimension != nil {
+		opts = append(opts, WithProductQuantizationSubvectorDimension(*spec.ProductQuantizationSubvectorDimension))
+	}
+	if spec.ProductQuantizationNumBits != nil {
+		opts = append(opts, WithProductQuantizationNumBits(*spec.ProductQuantizationNumBits))
+	}
+	if spec.ProductQuantizationNumTrainingRows != nil {
+		opts = append(opts, WithProductQuantizationNumTrainingRows(*spec.ProductQuantizationNumTrainingRows))
+	}
+	if spec.InitialNumCentroids != nil {
+		opts = append(opts, WithInitialNumCentroids(*spec.InitialNumCentroids))
+	}
+	if spec.NumDataPointsForClustering != nil {
+		opts = append(opts, WithNumDataPointsForClustering(*spec.NumDataPointsForClustering))
+	}
+	if spec.MaxClustersPerVector != nil {
+		opts = append(opts, WithMaxClustersPerVector(*spec.MaxClustersPerVector))
+	}
+	if spec.ClusteringDistanceThresholdPct != nil {
+		opts = append(opts, WithClusteringDistanceThresholdPct(*spec.ClusteringDistanceThresholdPct))
+	}
+	if spec.PostingListEncodingType != nil {
+		encodingType, ok := IntSeqEncodingTypes[*spec.PostingListEncodingType]
+		if !ok {
+			return nil, fmt.Errorf("unknown posting list encoding type %q", *spec.PostingListEncodingType)
+		}
+		opts = append(opts, WithPostingListEncodingType(encodingType))
+	}
+	if spec.PostingListBuilderVectorStorageMemorySize != nil {
+		opts = append(opts, WithPostingListBuilderVectorStorageMemorySize(*spec.PostingListBuilderVectorStorageMemorySize))
+	}
+	if spec.PostingListBuilderVectorStorageFileSize != nil {
+		opts = append(opts, WithPostingListBuilderVectorStorageFileSize(*spec.PostingListBuilderVectorStorageFileSize))
+	}
+	if spec.MaxPostingListSize != nil {
+		opts = append(opts, WithMaxPostingListSize(*spec.MaxPostingListSize))
+	}
+	if spec.PostingListKmeansUnbalancedPenalty != nil {
+		opts = append(opts, WithPostingListKmeansUnbalancedPenalty(*spec.PostingListKmeansUnbalancedPenalty))
+	}
+	if spec.Reindex != nil {
+		opts = append(opts, WithReindex(*spec.Reindex))
+	}
+	if spec.WalFileSize != nil {
+		opts = append(opts, WithWalFileSize(*spec.WalFileSize))
+	}
+	if spec.MaxPendingOps != nil {
+		opts = append(opts, WithMaxPendingOps(*spec.MaxPendingOps))
+	}
+	if spec.MaxTimeToFlushMs != nil {
+		opts = append(opts, WithMaxTimeToFlushMs(*spec.MaxTimeToFlushMs))
+	}
+	return NewCollectionBuilder(spec.CollectionName, opts...)
+}
@@ -0,0 +1,94 @@
+package muopdbclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrNoDocuments = errors.New("no documents to insert")
+
+var ErrEmptyVector = errors.New("query vector is empty")
+
+// DimensionMismatchError is returned when vectors do not have the number of
+// features the collection was created with.
+type DimensionMismatchError struct {
+	CollectionName string
+	Expected       uint32
+	Actual         int
+}
+
+func (e *DimensionMismatchError) Error() string {
+	return fmt.Sprintf("collection %q expects vectors with %d features, got %d",
+		e.CollectionName, e.Expected, e.Actual)
+}
+
+// MisalignedVectorsError is returned when the flattened vectors of an insert
+// cannot be split evenly between its documents.
+type MisalignedVectorsError struct {
+	CollectionName string
+	NumValues      int
+	NumDocs        int
+}
+
+func (e *MisalignedVectorsError) Error() string {
+	return fmt.Sprintf("collection %q: %d vector values cannot be split between %d documents",
+		e.CollectionName, e.NumValues, e.NumDocs)
+}
+
+// collectionSchemas remembers the number of features of known collections.
+type collectionSchemas struct {
+	mu          sync.RWMutex
+	numFeatures map[string]uint32
+}
+
+func newCollectionSchemas() *collectionSchemas {
+	return &collectionSchemas{
+		numFeatures: make(map[string]uint32),
+	}
+}
+
+func (s *collectionSchemas) register(builder *CollectionBuilder) {
+	if builder == nil || builder.NumFeatures == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.numFeatures[builder.CollectionName] = *builder.NumFeatures
+}
+
+func (s *collectionSchemas) validateInsert(collectionName string, numDocs int, vectors []float32) error {
+	if numDocs == 0 {
+		return ErrNoDocuments
+	}
+	if len(vectors) == 0 || len(vectors)%numDocs != 0 {
+		return &MisalignedVectorsError{
+			CollectionName: collectionName,
+			NumValues:      len(vectors),
+			NumDocs:        numDocs,
+		}
+	}
+	return s.validateDimension(collectionName, len(vectors)/numDocs)
+}
+
+func (s *collectionSchemas) validateSearch(collectionName string, vector []float32) error {
+	if len(vector) == 0 {
+		return ErrEmptyVector
+	}
+	return s.validateDimension(collectionName, len(vector))
+}
+
+func (s *collectionSchemas) validateDimension(collectionName string, dimension int) error {
+	s.mu.RLock()
+	expected, ok := s.numFeatures[collectionName]
+	s.mu.RUnlock()
+
+	if ok && int(expected) != dimension {
+		return &DimensionMismatchError{
+			CollectionName: collectionName,
+			Expected:       expected,
+			Actual:         dimension,
+		}
+	}
+	return nil
+}
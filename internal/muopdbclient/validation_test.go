@@ -0,0 +1,127 @@
+package muopdbclient
+
+import (
+	"context"
+	"errors"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"google.golang.org/grpc"
+	"sync/atomic"
+	"testing"
+)
+
+// newValidatingClient returns a client knowing that "docs" has 3 features, and
+// the number of calls that reached the server.
+func newValidatingClient(t *testing.T) (MuopDbClient, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := muopdbtest.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		calls.Add(1)
+		return handler(ctx, req)
+	}))
+	t.Cleanup(server.Close)
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(conn, WithFlushPolicy(FlushPolicy{}))
+	t.Cleanup(func() { client.Close() })
+
+	if err := client.CreateCollection(context.Background(), "docs"); err != nil {
+		t.Fatal(err)
+	}
+	builder, err := NewCollectionBuilder("docs", WithNumFeatures(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.RegisterCollection(builder)
+	calls.Store(0)
+	return client, &calls
+}
+
+// inserts sends the same documents through Insert and InsertPacked.
+func inserts(client MuopDbClient, collectionName string, docIds []DocID, vectors []float32) map[string]error {
+	users := []DocID{{}}
+	_, insertErr := client.Insert(context.Background(), InsertRequest{
+		CollectionName: collectionName, DocIds: docIds, Vectors: vectors, UserIds: users,
+	})
+	_, packedErr := client.InsertPacked(context.Background(), InsertPackedRequest{
+		CollectionName: collectionName, DocIds: docIds, Vectors: vectors, UserIds: users,
+	})
+	return map[string]error{"Insert": insertErr, "InsertPacked": packedErr}
+}
+
+func TestInsertValidation(t *testing.T) {
+	client, calls := newValidatingClient(t)
+	twoDocs := []DocID{NewDocIDFromUint64(1), NewDocIDFromUint64(2)}
+
+	for method, err := range inserts(client, "docs", twoDocs, []float32{1, 2, 3, 4}) {
+		var mismatch *DimensionMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Errorf("%s of 2 features returned %v, want a DimensionMismatchError", method, err)
+			continue
+		}
+		if *mismatch != (DimensionMismatchError{CollectionName: "docs", Expected: 3, Actual: 2}) {
+			t.Errorf("%s returned %+v", method, *mismatch)
+		}
+	}
+
+	for _, vectors := range [][]float32{{1, 2, 3, 4, 5}, nil} {
+		for method, err := range inserts(client, "docs", twoDocs, vectors) {
+			var misaligned *MisalignedVectorsError
+			if !errors.As(err, &misaligned) {
+				t.Errorf("%s of %d values for 2 documents returned %v, want a MisalignedVectorsError", method, len(vectors), err)
+				continue
+			}
+			if *misaligned != (MisalignedVectorsError{CollectionName: "docs", NumValues: len(vectors), NumDocs: 2}) {
+				t.Errorf("%s returned %+v", method, *misaligned)
+			}
+		}
+	}
+
+	for method, err := range inserts(client, "docs", nil, []float32{1, 2, 3}) {
+		if !errors.Is(err, ErrNoDocuments) {
+			t.Errorf("%s without documents returned %v, want ErrNoDocuments", method, err)
+		}
+	}
+
+	if n := calls.Load(); n != 0 {
+		t.Errorf("%d invalid inserts reached the server", n)
+	}
+
+	for method, err := range inserts(client, "docs", twoDocs, []float32{1, 2, 3, 4, 5, 6}) {
+		if err != nil {
+			t.Errorf("%s of valid documents: %v", method, err)
+		}
+	}
+	// Collections the client was not told about are left to the server.
+	for method, err := range inserts(client, "other", twoDocs, []float32{1, 2, 3, 4}) {
+		var mismatch *DimensionMismatchError
+		if errors.As(err, &mismatch) {
+			t.Errorf("%s into an unregistered collection was validated locally: %v", method, err)
+		}
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	client, calls := newValidatingClient(t)
+	search := func(vector []float32) error {
+		_, err := client.Search(context.Background(), SearchRequest{
+			CollectionName: "docs", Vector: vector, TopK: 1, UserIds: []DocID{{}},
+		})
+		return err
+	}
+
+	var mismatch *DimensionMismatchError
+	if err := search([]float32{1, 2, 3, 4}); !errors.As(err, &mismatch) || mismatch.Expected != 3 || mismatch.Actual != 4 {
+		t.Errorf("a search of 4 features returned %v, want a DimensionMismatchError", err)
+	}
+	if err := search(nil); !errors.Is(err, ErrEmptyVector) {
+		t.Errorf("an empty search returned %v, want ErrEmptyVector", err)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("%d invalid searches reached the server", n)
+	}
+	if err := search([]float32{1, 2, 3}); err != nil {
+		t.Errorf("a valid search: %v", err)
+	}
+}
@@ -0,0 +1,337 @@
+package muopdbtest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	pb "github.com/TrungBui59/test_muopdb/api/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"sort"
+	"sync"
+)
+
+// id is a 128-bit doc or user id.
+type id struct {
+	low  uint64
+	high uint64
+}
+
+type docKey struct {
+	doc  id
+	user id
+}
+
+type collection struct {
+	numFeatures uint32
+	// docs holds every document searchable in the collection.
+	docs map[docKey][]float32
+	// pending are the documents inserted since the last flush.
+	pending       map[docKey]struct{}
+	segments      map[string]map[docKey]struct{}
+	segmentOrder  []string
+	nextSegmentID int
+}
+
+// IndexServer is an in-memory pb.IndexServerServer. Search is a brute-force scan
+// scoring documents by squared L2 distance, lower is closer. Documents are
+// searchable as soon as they are inserted; Flush groups the documents inserted
+// since the previous flush into a new segment.
+type IndexServer struct {
+	pb.UnimplementedIndexServerServer
+
+	mu          sync.Mutex
+	collections map[string]*collection
+}
+
+func NewIndexServer() *IndexServer {
+	return &IndexServer{
+		collections: make(map[string]*collection),
+	}
+}
+
+// NumDocuments returns how many (doc id, user id) pairs the collection holds.
+func (s *IndexServer) NumDocuments(collectionName string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collections[collectionName]
+	if !ok {
+		return 0
+	}
+	return len(c.docs)
+}
+
+func (s *IndexServer) getCollection(collectionName string) (*collection, error) {
+	c, ok := s.collections[collectionName]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "collection %q does not exist", collectionName)
+	}
+	return c, nil
+}
+
+func (s *IndexServer) CreateCollection(_ context.Context, request *pb.CreateCollectionRequest) (*pb.CreateCollectionResponse, error) {
+	if request.CollectionName == "" {
+		return nil, status.Error(codes.InvalidArgument, "collection name cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[request.CollectionName]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "collection %q already exists", request.CollectionName)
+	}
+	s.collections[request.CollectionName] = &collection{
+		numFeatures: request.GetNumFeatures(),
+		docs:        make(map[docKey][]float32),
+		pending:     make(map[docKey]struct{}),
+		segments:    make(map[string]map[docKey]struct{}),
+	}
+	return &pb.CreateCollectionResponse{}, nil
+}
+
+func (s *IndexServer) Insert(_ context.Context, request *pb.InsertRequest) (*pb.InsertResponse, error) {
+	numInserted, err := s.insert(request.CollectionName, request.LowIds, request.HighIds,
+		request.Vectors, request.LowUserIds, request.HighUserIds)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.InsertResponse{NumDocsInserted: numInserted}, nil
+}
+
+func (s *IndexServer) InsertPacked(_ context.Context, request *pb.InsertPackedRequest) (*pb.InsertPackedResponse, error) {
+	lowIds, err := unpackUint64s(request.LowIds)
+	if err != nil {
+		return nil, err
+	}
+	highIds, err := unpackUint64s(request.HighIds)
+	if err != nil {
+		return nil, err
+	}
+	if len(request.Vectors)%4 != 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "packed vectors length %d is not a multiple of 4", len(request.Vectors))
+	}
+	vectors := make([]float32, len(request.Vectors)/4)
+	for i := range vectors {
+		vectors[i] = math.Float32frombits(binary.LittleEndian.Uint32(request.Vectors[i*4:]))
+	}
+
+	numInserted, err := s.insert(request.CollectionName, lowIds, highIds,
+		vectors, request.LowUserIds, request.HighUserIds)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.InsertPackedResponse{NumDocsInserted: numInserted}, nil
+}
+
+func unpackUint64s(packed []byte) ([]uint64, error) {
+	if len(packed)%8 != 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "packed ids length %d is not a multiple of 8", len(packed))
+	}
+	values := make([]uint64, len(packed)/8)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint64(packed[i*8:])
+	}
+	return values, nil
+}
+
+func (s *IndexServer) insert(collectionName string, lowIds, highIds []uint64, vectors []float32,
+	lowUserIds, highUserIds []uint64) (uint32, error) {
+	if len(lowIds) == 0 || len(lowIds) != len(highIds) {
+		return 0, status.Errorf(codes.InvalidArgument, "got %d low ids and %d high ids", len(lowIds), len(highIds))
+	}
+	if len(lowUserIds) == 0 || len(lowUserIds) != len(highUserIds) {
+		return 0, status.Errorf(codes.InvalidArgument, "got %d low user ids and %d high user ids",
+			len(lowUserIds), len(highUserIds))
+	}
+	if len(vectors)%len(lowIds) != 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "%d vector values cannot be split between %d documents",
+			len(vectors), len(lowIds))
+	}
+	dimension := len(vectors) / len(lowIds)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.getCollection(collectionName)
+	if err != nil {
+		return 0, err
+	}
+	if c.numFeatures == 0 {
+		c.numFeatures = uint32(dimension)
+	}
+	if int(c.numFeatures) != dimension {
+		return 0, status.Errorf(codes.InvalidArgument, "collection %q expects %d features, got %d",
+			collectionName, c.numFeatures, dimension)
+	}
+
+	for i := range lowIds {
+		vector := append([]float32(nil), vectors[i*dimension:(i+1)*dimension]...)
+		for j := range lowUserIds {
+			key := docKey{
+				doc:  id{low: lowIds[i], high: highIds[i]},
+				user: id{low: lowUserIds[j], high: highUserIds[j]},
+			}
+			c.docs[key] = vector
+			c.pending[key] = struct{}{}
+		}
+	}
+	return uint32(len(lowIds)), nil
+}
+
+type scoredDoc struct {
+	doc   id
+	score float32
+}
+
+func (s *IndexServer) Search(_ context.Context, request *pb.SearchRequest) (*pb.SearchResponse, error) {
+	if len(request.LowUserIds) != len(request.HighUserIds) {
+		return nil, status.Errorf(codes.InvalidArgument, "got %d low user ids and %d high user ids",
+			len(request.LowUserIds), len(request.HighUserIds))
+	}
+	users := make(map[id]struct{}, len(request.LowUserIds))
+	for i := range request.LowUserIds {
+		users[id{low: request.LowUserIds[i], high: request.HighUserIds[i]}] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.getCollection(request.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+	if c.numFeatures != 0 && int(c.numFeatures) != len(request.Vector) {
+		return nil, status.Errorf(codes.InvalidArgument, "collection %q expects %d features, got %d",
+			request.CollectionName, c.numFeatures, len(request.Vector))
+	}
+
+	// A doc inserted for several of the requested users is only returned once.
+	best := make(map[id]float32)
+	var numScanned uint64
+	for key, vector := range c.docs {
+		if _, ok := users[key.user]; !ok {
+			continue
+		}
+		numScanned++
+		score := squaredL2(request.Vector, vector)
+		if current, ok := best[key.doc]; !ok || score < current {
+			best[key.doc] = score
+		}
+	}
+
+	results := make([]scoredDoc, 0, len(best))
+	for doc, score := range best {
+		results = append(results, scoredDoc{doc: doc, score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score < results[j].score
+		}
+		if results[i].doc.high != results[j].doc.high {
+			return results[i].doc.high < results[j].doc.high
+		}
+		return results[i].doc.low < results[j].doc.low
+	})
+	if len(results) > int(request.TopK) {
+		results = results[:request.TopK]
+	}
+
+	response := &pb.SearchResponse{}
+	for _, result := range results {
+		response.LowIds = append(response.LowIds, result.doc.low)
+		response.HighIds = append(response.HighIds, result.doc.high)
+		response.Scores = append(response.Scores, result.score)
+	}
+	if request.RecordMetrics {
+		response.NumPagesAccessed = numScanned
+	}
+	return response, nil
+}
+
+func squaredL2(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+	return sum
+}
+
+func (s *IndexServer) Flush(_ context.Context, request *pb.FlushRequest) (*pb.FlushResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.getCollection(request.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.pending) == 0 {
+		return &pb.FlushResponse{}, nil
+	}
+
+	name := c.newSegment(c.pending)
+	c.pending = make(map[docKey]struct{})
+	return &pb.FlushResponse{FlushedSegments: []string{name}}, nil
+}
+
+func (c *collection) newSegment(docs map[docKey]struct{}) string {
+	name := fmt.Sprintf("segment_%d", c.nextSegmentID)
+	c.nextSegmentID++
+	c.segments[name] = docs
+	c.segmentOrder = append(c.segmentOrder, name)
+	return name
+}
+
+func (s *IndexServer) GetSegments(_ context.Context, request *pb.GetSegmentsRequest) (*pb.GetSegmentsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.getCollection(request.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetSegmentsResponse{
+		SegmentNames: append([]string(nil), c.segmentOrder...),
+	}, nil
+}
+
+func (s *IndexServer) CompactSegments(_ context.Context, request *pb.CompactSegmentsRequest) (*pb.CompactSegmentsResponse, error) {
+	if len(request.SegmentNames) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no segments to compact")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.getCollection(request.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	toCompact := make(map[string]struct{}, len(request.SegmentNames))
+	for _, name := range request.SegmentNames {
+		if _, ok := c.segments[name]; !ok {
+			return nil, status.Errorf(codes.NotFound, "segment %q does not exist in collection %q",
+				name, request.CollectionName)
+		}
+		toCompact[name] = struct{}{}
+	}
+
+	merged := make(map[docKey]struct{})
+	remaining := c.segmentOrder[:0]
+	for _, name := range c.segmentOrder {
+		if _, ok := toCompact[name]; !ok {
+			remaining = append(remaining, name)
+			continue
+		}
+		for key := range c.segments[name] {
+			merged[key] = struct{}{}
+		}
+		delete(c.segments, name)
+	}
+	c.segmentOrder = remaining
+	c.newSegment(merged)
+	return &pb.CompactSegmentsResponse{}, nil
+}
@@ -0,0 +1,57 @@
+// Package muopdbtest provides an in-memory MuopDB index server served over an
+// in-process gRPC connection, so code talking to MuopDB can be tested without
+// running the real server.
+package muopdbtest
+
+import (
+	"context"
+	pb "github.com/TrungBui59/test_muopdb/api/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+)
+
+const bufSize = 1 << 20
+
+// Server serves an IndexServer over a bufconn listener.
+type Server struct {
+	IndexServer *IndexServer
+
+	listener   *bufconn.Listener
+	grpcServer *grpc.Server
+}
+
+// NewServer starts serving a fresh IndexServer. The server options can be used
+// to install interceptors, e.g. to inject failures.
+func NewServer(opts ...grpc.ServerOption) *Server {
+	s := &Server{
+		IndexServer: NewIndexServer(),
+		listener:    bufconn.Listen(bufSize),
+		grpcServer:  grpc.NewServer(opts...),
+	}
+	pb.RegisterIndexServerServer(s.grpcServer, s.IndexServer)
+
+	go func() {
+		// Serve only returns once the server is stopped.
+		_ = s.grpcServer.Serve(s.listener)
+	}()
+	return s
+}
+
+// Dial returns a client connection to the server. Callers close it.
+func (s *Server) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, opts...)
+	return grpc.NewClient("passthrough:///bufnet", opts...)
+}
+
+// Close stops the server and closes its listener.
+func (s *Server) Close() {
+	s.grpcServer.Stop()
+	_ = s.listener.Close()
+}
@@ -0,0 +1,226 @@
+package muopdbtest_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"slices"
+	"testing"
+)
+
+var (
+	alice = muopdbclient.NewDocIDFromUint64(1)
+	bob   = muopdbclient.NewDocIDFromUint64(2)
+)
+
+func newClient(t *testing.T) (*muopdbtest.Server, muopdbclient.MuopDbClient) {
+	t.Helper()
+	server := muopdbtest.NewServer()
+	t.Cleanup(server.Close)
+
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := muopdbclient.NewClient(conn, muopdbclient.WithFlushPolicy(muopdbclient.FlushPolicy{}))
+	t.Cleanup(func() { client.Close() })
+
+	builder, err := muopdbclient.NewCollectionBuilder("docs", muopdbclient.WithNumFeatures(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateCollectionFromBuilder(context.Background(), builder); err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+func search(t *testing.T, client muopdbclient.MuopDbClient, vector []float32, users ...muopdbclient.DocID) muopdbclient.SearchResponse {
+	t.Helper()
+	response, err := client.Search(context.Background(), muopdbclient.SearchRequest{
+		CollectionName: "docs",
+		Vector:         vector,
+		TopK:           10,
+		UserIds:        users,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestInsertRoundTrip(t *testing.T) {
+	server, client := newClient(t)
+	ids := []muopdbclient.DocID{
+		muopdbclient.NewDocIDFromUint64(7),
+		{Low: 8, High: 0xabcdef},
+	}
+
+	response, err := client.Insert(context.Background(), muopdbclient.InsertRequest{
+		CollectionName: "docs",
+		DocIds:         ids,
+		Vectors:        []float32{0, 0, 3, 4},
+		UserIds:        []muopdbclient.DocID{alice},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.NumDocsInserted != 2 || server.IndexServer.NumDocuments("docs") != 2 {
+		t.Fatalf("inserted %d docs, the server holds %d, want 2", response.NumDocsInserted, server.IndexServer.NumDocuments("docs"))
+	}
+
+	results := search(t, client, []float32{0, 0}, alice)
+	if !slices.Equal(results.DocIds, ids) {
+		t.Errorf("search returned %v, want %v", results.DocIds, ids)
+	}
+	if !slices.Equal(results.Scores, []float32{0, 25}) {
+		t.Errorf("scores = %v, want squared L2 distances [0 25]", results.Scores)
+	}
+}
+
+func TestInsertPackedRoundTrip(t *testing.T) {
+	server, client := newClient(t)
+	ids := []muopdbclient.DocID{
+		{Low: 1, High: 1},
+		{Low: 0xffffffffffffffff, High: 2},
+		muopdbclient.NewDocIDFromUint64(3),
+	}
+
+	response, err := client.InsertPacked(context.Background(), muopdbclient.InsertPackedRequest{
+		CollectionName: "docs",
+		DocIds:         ids,
+		Vectors:        []float32{1, 1, -2.5, 0.5, 10, 10},
+		UserIds:        []muopdbclient.DocID{alice},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.NumDocsInserted != 3 || server.IndexServer.NumDocuments("docs") != 3 {
+		t.Fatalf("inserted %d docs, the server holds %d, want 3", response.NumDocsInserted, server.IndexServer.NumDocuments("docs"))
+	}
+
+	// Each vector is found at distance zero under its own id, so ids and
+	// vectors were unpacked in the same order they were packed.
+	vectors := [][]float32{{1, 1}, {-2.5, 0.5}, {10, 10}}
+	for i, vector := range vectors {
+		results := search(t, client, vector, alice)
+		if len(results.DocIds) == 0 || results.DocIds[0] != ids[i] || results.Scores[0] != 0 {
+			t.Errorf("searching %v returned %v with scores %v, want %v first", vector, results.DocIds, results.Scores, ids[i])
+		}
+	}
+}
+
+func TestSearchFiltersByUser(t *testing.T) {
+	_, client := newClient(t)
+	insert := func(user muopdbclient.DocID, ids ...uint64) {
+		t.Helper()
+		request := muopdbclient.InsertRequest{CollectionName: "docs", UserIds: []muopdbclient.DocID{user}}
+		for _, id := range ids {
+			request.DocIds = append(request.DocIds, muopdbclient.NewDocIDFromUint64(id))
+			request.Vectors = append(request.Vectors, float32(id), 0)
+		}
+		if _, err := client.Insert(context.Background(), request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert(alice, 1, 2)
+	insert(bob, 3)
+
+	ids := func(values ...uint64) []muopdbclient.DocID {
+		var docIds []muopdbclient.DocID
+		for _, value := range values {
+			docIds = append(docIds, muopdbclient.NewDocIDFromUint64(value))
+		}
+		return docIds
+	}
+	tests := []struct {
+		name  string
+		users []muopdbclient.DocID
+		want  []muopdbclient.DocID
+	}{
+		{name: "alice", users: []muopdbclient.DocID{alice}, want: ids(1, 2)},
+		{name: "bob", users: []muopdbclient.DocID{bob}, want: ids(3)},
+		{name: "both", users: []muopdbclient.DocID{alice, bob}, want: ids(1, 2, 3)},
+		{name: "nobody", users: []muopdbclient.DocID{muopdbclient.NewDocIDFromUint64(99)}, want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := search(t, client, []float32{0, 0}, test.users...)
+			if !slices.Equal(results.DocIds, test.want) {
+				t.Errorf("search returned %v, want %v", results.DocIds, test.want)
+			}
+		})
+	}
+}
+
+func TestFlushAndCompactSegments(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+
+	flush := func() []string {
+		t.Helper()
+		response, err := client.Flush(ctx, muopdbclient.FlushRequest{CollectionName: "docs"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.FlushedSegments
+	}
+	segments := func() []string {
+		t.Helper()
+		response, err := client.GetSegments(ctx, muopdbclient.GetSegmentsRequest{CollectionName: "docs"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.SegmentNames
+	}
+
+	if flushed := flush(); len(flushed) != 0 {
+		t.Errorf("flushing an empty collection created %v", flushed)
+	}
+	for i := range 2 {
+		_, err := client.Insert(ctx, muopdbclient.InsertRequest{
+			CollectionName: "docs",
+			DocIds:         []muopdbclient.DocID{muopdbclient.NewDocIDFromUint64(uint64(i))},
+			Vectors:        []float32{float32(i), 0},
+			UserIds:        []muopdbclient.DocID{alice},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flushed, want := flush(), []string{fmt.Sprintf("segment_%d", i)}; !slices.Equal(flushed, want) {
+			t.Errorf("flush %d created %v, want %v", i, flushed, want)
+		}
+	}
+	if got, want := segments(), []string{"segment_0", "segment_1"}; !slices.Equal(got, want) {
+		t.Fatalf("segments = %v, want %v", got, want)
+	}
+
+	if _, err := client.CompactAllSegments(ctx, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := segments(), []string{"segment_2"}; !slices.Equal(got, want) {
+		t.Errorf("segments after compacting = %v, want %v", got, want)
+	}
+	if results := search(t, client, []float32{0, 0}, alice); len(results.DocIds) != 2 {
+		t.Errorf("search after compacting returned %v, want both documents", results.DocIds)
+	}
+
+	_, err := client.CompactSegments(ctx, muopdbclient.CompactSegmentsRequest{
+		CollectionName: "docs",
+		SegmentNames:   []string{"segment_0"},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("compacting a compacted segment returned %v, want NotFound", err)
+	}
+}
+
+func TestUnknownCollection(t *testing.T) {
+	_, client := newClient(t)
+	_, err := client.GetSegments(context.Background(), muopdbclient.GetSegmentsRequest{CollectionName: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetSegments of a missing collection returned %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,104 @@
+package sweep
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// sorted returns the results by decreasing recall, then increasing median
+// latency, with the failed ones last.
+func sorted(results []Result) []Result {
+	out := append([]Result{}, results...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		if a.Recall != b.Recall {
+			return a.Recall > b.Recall
+		}
+		return a.Latency.P50 < b.Latency.P50
+	})
+	return out
+}
+
+// WriteTable writes the results as an aligned table, the Pareto optimal ones
+// marked with a star. paretoOnly leaves the others out.
+func WriteTable(w io.Writer, results []Result, paretoOnly bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tRECALL\tMRR\tP50\tP99\tBUILD\tEF\tPARAMETERS")
+	for _, result := range sorted(results) {
+		if paretoOnly && !result.Pareto {
+			continue
+		}
+		if result.Error != "" {
+			fmt.Fprintf(tw, "!\t-\t-\t-\t-\t-\t-\t%s: %s\n", FormatParameters(result.Parameters), result.Error)
+			continue
+		}
+		mark := ""
+		if result.Pareto {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%v\t%v\t%v\t%d\t%s\n", mark, result.Recall, result.MRR,
+			result.Latency.P50.Round(time.Microsecond), result.Latency.P99.Round(time.Microsecond),
+			result.BuildTime.Round(time.Millisecond), result.EfConstruction, FormatParameters(result.Parameters))
+	}
+	return tw.Flush()
+}
+
+func WriteJSON(w io.Writer, results []Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sorted(results))
+}
+
+// WriteCSV writes one row per result with a column per swept parameter.
+func WriteCSV(w io.Writer, results []Result) error {
+	nameSet := make(map[string]bool)
+	for _, result := range results {
+		for name := range result.Parameters {
+			nameSet[name] = true
+		}
+	}
+	names := make([]string, 0, len(nameSet))
+	for name := range nameSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writer := csv.NewWriter(w)
+	header := append([]string{"collection", "pareto", "recall_at_k", "mrr", "p50_us", "p99_us", "build_time_s",
+		"ef_construction", "error"}, names...)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	float := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	micros := func(d time.Duration) string { return float(float64(d) / float64(time.Microsecond)) }
+	for _, result := range sorted(results) {
+		row := []string{
+			result.Collection, strconv.FormatBool(result.Pareto), float(result.Recall), float(result.MRR),
+			micros(result.Latency.P50), micros(result.Latency.P99), float(result.BuildTime.Seconds()),
+			strconv.FormatUint(uint64(result.EfConstruction), 10), result.Error,
+		}
+		for _, name := range names {
+			value, ok := result.Parameters[name]
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, fmt.Sprint(value))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,214 @@
+// Package sweep builds a temporary collection for every configuration of a
+// search space, measures its recall, latency and build time, and picks out the
+// configurations no other one beats on all three.
+package sweep
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"gopkg.in/yaml.v2"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	ModeGrid   = "grid"
+	ModeRandom = "random"
+)
+
+// Spec describes a sweep. Parameters use the CollectionSpec names, as in the
+// REST API, for example:
+//
+//	mode: grid
+//	base: samples/base.fvecs
+//	queries: samples/queries.fvecs
+//	k: 10
+//	fixed:
+//	  quantization_type: NO_QUANTIZER
+//	parameters:
+//	  centroids_max_neighbors: [10, 20, 40]
+//	  max_posting_list_size: [1000, 5000]
+//	ef_construction: [50, 100, 200]
+type Spec struct {
+	// Mode is "grid", every combination of the parameters, or "random",
+	// Samples combinations drawn with Seed.
+	Mode    string `yaml:"mode"`
+	Samples int    `yaml:"samples"`
+	Seed    int64  `yaml:"seed"`
+
+	// Base is inserted in every collection. GroundTruth is optional, the exact
+	// neighbors of the queries are computed from Base otherwise.
+	Base        string `yaml:"base"`
+	Queries     string `yaml:"queries"`
+	GroundTruth string `yaml:"ground_truth"`
+	MaxQueries  int    `yaml:"max_queries"`
+	Metric      string `yaml:"metric"`
+	K           uint32 `yaml:"k"`
+
+	CollectionPrefix string `yaml:"collection_prefix"`
+	// Fixed applies to every configuration, Parameters lists the values tried.
+	Fixed      map[string]any   `yaml:"fixed"`
+	Parameters map[string][]any `yaml:"parameters"`
+	// EfConstruction is swept at search time, without rebuilding the
+	// collection.
+	EfConstruction []uint32 `yaml:"ef_construction"`
+}
+
+func LoadSpec(path string) (Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, err
+	}
+
+	spec := Spec{
+		Mode:             ModeGrid,
+		Seed:             1,
+		Metric:           "l2",
+		K:                10,
+		CollectionPrefix: "sweep",
+	}
+	if err := yaml.UnmarshalStrict(data, &spec); err != nil {
+		return Spec{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(spec.EfConstruction) == 0 {
+		spec.EfConstruction = []uint32{100}
+	}
+
+	if err := spec.validate(); err != nil {
+		return Spec{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+func (s Spec) validate() error {
+	if s.Base == "" || s.Queries == "" {
+		return errors.New("base and queries are required")
+	}
+	if s.Mode != ModeGrid && s.Mode != ModeRandom {
+		return fmt.Errorf("unknown mode %q, expected %q or %q", s.Mode, ModeGrid, ModeRandom)
+	}
+	if s.Mode == ModeRandom && s.Samples <= 0 {
+		return errors.New("random mode needs a positive number of samples")
+	}
+	for name, values := range s.Parameters {
+		if len(values) == 0 {
+			return fmt.Errorf("parameter %s has no values", name)
+		}
+		if _, ok := s.Fixed[name]; ok {
+			return fmt.Errorf("parameter %s is both fixed and swept", name)
+		}
+	}
+
+	// Catch typos and bad values before building anything.
+	for _, params := range s.Configurations() {
+		if _, err := s.builder("validate", params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Configurations lists the swept parameter values of every collection to
+// build.
+func (s Spec) Configurations() []map[string]any {
+	names := make([]string, 0, len(s.Parameters))
+	for name := range s.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if s.Mode == ModeRandom {
+		return s.sample(names)
+	}
+
+	configurations := []map[string]any{{}}
+	for _, name := range names {
+		var next []map[string]any
+		for _, partial := range configurations {
+			for _, value := range s.Parameters[name] {
+				params := make(map[string]any, len(partial)+1)
+				for k, v := range partial {
+					params[k] = v
+				}
+				params[name] = value
+				next = append(next, params)
+			}
+		}
+		configurations = next
+	}
+	return configurations
+}
+
+// sample draws distinct configurations, fewer than Samples when the space is
+// smaller than that.
+func (s Spec) sample(names []string) []map[string]any {
+	size := 1
+	for _, name := range names {
+		size *= len(s.Parameters[name])
+		if size >= s.Samples {
+			break
+		}
+	}
+
+	random := rand.New(rand.NewSource(s.Seed))
+	seen := make(map[string]bool)
+	var configurations []map[string]any
+	for len(configurations) < min(s.Samples, size) {
+		params := make(map[string]any, len(names))
+		for _, name := range names {
+			values := s.Parameters[name]
+			params[name] = values[random.Intn(len(values))]
+		}
+		key := FormatParameters(params)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		configurations = append(configurations, params)
+	}
+	return configurations
+}
+
+// builder merges the fixed and swept parameters into a collection builder.
+func (s Spec) builder(collectionName string, params map[string]any) (*muopdbclient.CollectionBuilder, error) {
+	merged := map[string]any{"collection_name": collectionName}
+	for name, value := range s.Fixed {
+		merged[name] = value
+	}
+	for name, value := range params {
+		merged[name] = value
+	}
+
+	// Going through JSON gives the parameters the same names and checks as the
+	// REST API.
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("parameters %s: %w", FormatParameters(params), err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	var collectionSpec muopdbclient.CollectionSpec
+	if err := decoder.Decode(&collectionSpec); err != nil {
+		return nil, fmt.Errorf("parameters %s: %w", FormatParameters(params), err)
+	}
+	builder, err := collectionSpec.Builder()
+	if err != nil {
+		return nil, fmt.Errorf("parameters %s: %w", FormatParameters(params), err)
+	}
+	return builder, nil
+}
+
+// FormatParameters formats parameters as sorted name=value pairs.
+func FormatParameters(params map[string]any) string {
+	pairs := make([]string, 0, len(params))
+	for name, value := range params {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
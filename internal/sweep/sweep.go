@@ -0,0 +1,175 @@
+package sweep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/evaluation"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/vectorfile"
+	"time"
+)
+
+// Result measures one collection searched with one ef_construction. A
+// configuration that could not be built or searched only carries its error.
+type Result struct {
+	Collection     string                    `json:"collection"`
+	Parameters     map[string]any            `json:"parameters"`
+	EfConstruction uint32                    `json:"ef_construction"`
+	BuildTime      time.Duration             `json:"build_time_ns"`
+	Recall         float64                   `json:"recall_at_k"`
+	MRR            float64                   `json:"mrr"`
+	Latency        evaluation.LatencySummary `json:"latency"`
+	// Pareto is set when no other result has a higher recall, a lower median
+	// latency and a lower build time at once.
+	Pareto bool   `json:"pareto"`
+	Error  string `json:"error,omitempty"`
+}
+
+// recordIterator inserts the records of a vector file under their own ids.
+type recordIterator struct {
+	records *vectorfile.Reader
+}
+
+func (it recordIterator) Next() (muopdbclient.Row, error) {
+	record, err := it.records.Next()
+	if err != nil {
+		return muopdbclient.Row{}, err
+	}
+	return muopdbclient.Row{ID: record.ID, Vector: record.Vector}, nil
+}
+
+// Run builds a collection per configuration of the spec, one at a time, and
+// evaluates the queries against it. progress, if not nil, is called with
+// every result as it is measured. MuopDB cannot drop collections, so the
+// collections built are left on the server and named in the results. When ctx
+// is canceled, Run stops and returns the results measured so far, with their
+// Pareto marks, along with ctx.Err().
+func Run(ctx context.Context, client muopdbclient.MuopDbClient, spec Spec, queries [][]float32,
+	truth [][]muopdbclient.DocID, progress func(Result)) ([]Result, error) {
+	if len(queries) == 0 {
+		return nil, errors.New("no queries to evaluate")
+	}
+	runID := time.Now().Unix()
+
+	var results []Result
+	report := func(result Result) {
+		results = append(results, result)
+		if progress != nil {
+			progress(result)
+		}
+	}
+	finish := func(err error) ([]Result, error) {
+		markPareto(results)
+		return results, err
+	}
+
+	for i, params := range spec.Configurations() {
+		if err := ctx.Err(); err != nil {
+			return finish(err)
+		}
+		collectionName := fmt.Sprintf("%s-%d-%03d", spec.CollectionPrefix, runID, i)
+
+		buildTime, err := build(ctx, client, spec, collectionName, params, len(queries[0]))
+		// A configuration cut short by the cancellation did not fail.
+		if err := ctx.Err(); err != nil {
+			return finish(err)
+		}
+		if err != nil {
+			report(Result{Collection: collectionName, Parameters: params, Error: err.Error()})
+			continue
+		}
+
+		for _, ef := range spec.EfConstruction {
+			evaluated, err := evaluation.Evaluate(ctx, client, evaluation.SearchOptions{
+				CollectionName: collectionName,
+				TopK:           spec.K,
+				EfConstruction: ef,
+				UserIds:        []muopdbclient.DocID{{}},
+			}, queries, truth)
+			if err := ctx.Err(); err != nil {
+				return finish(err)
+			}
+			result := Result{
+				Collection:     collectionName,
+				Parameters:     params,
+				EfConstruction: ef,
+				BuildTime:      buildTime,
+				Recall:         evaluated.Recall,
+				MRR:            evaluated.MRR,
+				Latency:        evaluated.Latency,
+			}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			report(result)
+		}
+	}
+
+	return finish(nil)
+}
+
+// build creates the collection, inserts the base file and flushes it, and
+// returns how long that took.
+func build(ctx context.Context, client muopdbclient.MuopDbClient, spec Spec, collectionName string,
+	params map[string]any, dimension int) (time.Duration, error) {
+	if _, ok := spec.Fixed["num_features"]; !ok {
+		if _, ok := params["num_features"]; !ok {
+			withFeatures := map[string]any{"num_features": dimension}
+			for name, value := range params {
+				withFeatures[name] = value
+			}
+			params = withFeatures
+		}
+	}
+	builder, err := spec.builder(collectionName, params)
+	if err != nil {
+		return 0, err
+	}
+
+	base, err := vectorfile.Open(spec.Base)
+	if err != nil {
+		return 0, err
+	}
+	defer base.Close()
+
+	start := time.Now()
+	if err := client.CreateCollectionFromBuilder(ctx, builder); err != nil {
+		return 0, fmt.Errorf("creating %s: %w", collectionName, err)
+	}
+
+	inserter, err := muopdbclient.NewBulkInserter(client, collectionName)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := inserter.Run(ctx, recordIterator{records: base}); err != nil {
+		return 0, fmt.Errorf("inserting into %s: %w", collectionName, err)
+	}
+
+	_, err = client.Flush(ctx, muopdbclient.FlushRequest{CollectionName: collectionName})
+	if err != nil {
+		return 0, fmt.Errorf("flushing %s: %w", collectionName, err)
+	}
+	return time.Since(start), nil
+}
+
+func markPareto(results []Result) {
+	dominates := func(a, b Result) bool {
+		noWorse := a.Recall >= b.Recall && a.Latency.P50 <= b.Latency.P50 && a.BuildTime <= b.BuildTime
+		better := a.Recall > b.Recall || a.Latency.P50 < b.Latency.P50 || a.BuildTime < b.BuildTime
+		return noWorse && better
+	}
+
+	for i := range results {
+		if results[i].Error != "" {
+			continue
+		}
+		results[i].Pareto = true
+		for j := range results {
+			if i != j && results[j].Error == "" && dominates(results[j], results[i]) {
+				results[i].Pareto = false
+				break
+			}
+		}
+	}
+}
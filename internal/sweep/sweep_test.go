@@ -0,0 +1,153 @@
+package sweep
+
+import (
+	"context"
+	"errors"
+	"github.com/TrungBui59/test_muopdb/internal/evaluation"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbtest"
+	"github.com/TrungBui59/test_muopdb/internal/vectorfile"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func result(recall float64, p50, buildTime time.Duration) Result {
+	return Result{Recall: recall, Latency: evaluation.LatencySummary{P50: p50}, BuildTime: buildTime}
+}
+
+func TestMarkPareto(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []Result
+		want    []bool
+	}{
+		{
+			name:    "single",
+			results: []Result{result(0.5, time.Millisecond, time.Second)},
+			want:    []bool{true},
+		},
+		{
+			name: "dominated on every axis",
+			results: []Result{
+				result(0.9, time.Millisecond, time.Second),
+				result(0.8, 2*time.Millisecond, 2*time.Second),
+			},
+			want: []bool{true, false},
+		},
+		{
+			name: "trade offs",
+			results: []Result{
+				result(0.9, 2*time.Millisecond, time.Second),
+				result(0.8, time.Millisecond, time.Second),
+				result(0.7, time.Millisecond, time.Second/2),
+				result(0.7, time.Millisecond, time.Second),
+			},
+			want: []bool{true, true, true, false},
+		},
+		{
+			name: "equal results do not dominate each other",
+			results: []Result{
+				result(0.9, time.Millisecond, time.Second),
+				result(0.9, time.Millisecond, time.Second),
+			},
+			want: []bool{true, true},
+		},
+		{
+			name: "failures are never optimal nor dominate",
+			results: []Result{
+				{Error: "unavailable"},
+				result(0.1, time.Second, time.Hour),
+			},
+			want: []bool{false, true},
+		},
+	}
+	for _, test := range tests {
+		markPareto(test.results)
+		got := make([]bool, len(test.results))
+		for i, r := range test.results {
+			got[i] = r.Pareto
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Pareto = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func newSweep(t *testing.T) (muopdbclient.MuopDbClient, Spec, [][]float32, [][]muopdbclient.DocID) {
+	t.Helper()
+	server := muopdbtest.NewServer()
+	t.Cleanup(server.Close)
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := muopdbclient.NewClient(conn, muopdbclient.WithFlushPolicy(muopdbclient.FlushPolicy{}))
+	t.Cleanup(func() { client.Close() })
+
+	base := filepath.Join(t.TempDir(), "base.fvecs")
+	if err := vectorfile.Save(base, []vectorfile.Record{
+		{Vector: []float32{0, 0}}, {Vector: []float32{1, 0}}, {Vector: []float32{5, 5}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	spec := Spec{
+		Mode:             ModeGrid,
+		Base:             base,
+		K:                2,
+		CollectionPrefix: "sweep",
+		Parameters:       map[string][]any{"centroids_max_neighbors": {10, 20}},
+		EfConstruction:   []uint32{50, 100},
+	}
+	queries := [][]float32{{0, 0}, {5, 4}}
+	truth := [][]muopdbclient.DocID{
+		{muopdbclient.NewDocIDFromUint64(0), muopdbclient.NewDocIDFromUint64(1)},
+		{muopdbclient.NewDocIDFromUint64(2), muopdbclient.NewDocIDFromUint64(1)},
+	}
+	return client, spec, queries, truth
+}
+
+func TestRun(t *testing.T) {
+	client, spec, queries, truth := newSweep(t)
+	var progressed int
+	results, err := Run(context.Background(), client, spec, queries, truth, func(Result) { progressed++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || progressed != 4 {
+		t.Fatalf("got %d results and %d progress calls, want 2 configurations by 2 ef_construction", len(results), progressed)
+	}
+	pareto := 0
+	for _, r := range results {
+		if r.Error != "" || r.Recall != 1 || r.MRR != 1 || r.Latency.Count != 2 {
+			t.Errorf("result %+v, want an exact search of both queries", r)
+		}
+		if r.Pareto {
+			pareto++
+		}
+	}
+	if pareto == 0 {
+		t.Error("no result is marked Pareto optimal")
+	}
+	if results[0].Collection != results[1].Collection || results[1].Collection == results[2].Collection {
+		t.Errorf("the ef_construction values should share their configuration's collection: %+v", results)
+	}
+}
+
+func TestRunStopsWhenCanceled(t *testing.T) {
+	client, spec, queries, truth := newSweep(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, err := Run(ctx, client, spec, queries, truth, func(Result) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run returned %v, want context.Canceled", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want the one measured before the cancel: %+v", len(results), results)
+	}
+	if results[0].Error != "" || !results[0].Pareto {
+		t.Errorf("result %+v, want it measured and marked Pareto optimal", results[0])
+	}
+}
@@ -0,0 +1,35 @@
+package vectorfile
+
+import (
+	"encoding/gob"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"io"
+)
+
+// gobReader reads the single [][]float32 the CLI used to save embeddings as.
+// Gob cannot decode a slice piecemeal, so the whole file is held in memory.
+type gobReader struct {
+	vectors [][]float32
+	n       int
+}
+
+func newGobReader(r io.Reader) (*gobReader, error) {
+	var vectors [][]float32
+	if err := gob.NewDecoder(r).Decode(&vectors); err != nil {
+		return nil, err
+	}
+	return &gobReader{vectors: vectors}, nil
+}
+
+func (g *gobReader) read() (Record, error) {
+	if g.n == len(g.vectors) {
+		return Record{}, io.EOF
+	}
+	record := Record{ID: muopdbclient.NewDocIDFromUint64(uint64(g.n)), Vector: g.vectors[g.n]}
+	g.n++
+	return record, nil
+}
+
+func writeGob(w io.Writer, vectors [][]float32) error {
+	return gob.NewEncoder(w).Encode(vectors)
+}
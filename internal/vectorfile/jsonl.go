@@ -0,0 +1,76 @@
+package vectorfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"io"
+)
+
+type jsonlRecord struct {
+	// ID is optional when reading, records without one are numbered by their
+	// position in the file.
+	ID     *muopdbclient.DocID `json:"id,omitempty"`
+	Vector []float32           `json:"vector"`
+	Text   string              `json:"text,omitempty"`
+}
+
+// jsonlReader reads one {"id", "vector", "text"} object per line. Blank lines
+// are skipped.
+type jsonlReader struct {
+	r    *bufio.Reader
+	n    uint64
+	line int
+}
+
+func newJSONLReader(r *bufio.Reader) *jsonlReader {
+	return &jsonlReader{r: r}
+}
+
+func (j *jsonlReader) read() (Record, error) {
+	for {
+		line, err := j.r.ReadBytes('\n')
+		if len(line) > 0 {
+			j.line++
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return Record{}, err
+			}
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Record{}, err
+		}
+
+		var decoded jsonlRecord
+		if err := json.Unmarshal(line, &decoded); err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", j.line, err)
+		}
+		if len(decoded.Vector) == 0 {
+			return Record{}, fmt.Errorf("line %d: no vector", j.line)
+		}
+
+		record := Record{
+			ID:     muopdbclient.NewDocIDFromUint64(j.n),
+			Vector: decoded.Vector,
+			Text:   decoded.Text,
+		}
+		if decoded.ID != nil {
+			record.ID = *decoded.ID
+		}
+		j.n++
+		return record, nil
+	}
+}
+
+func writeJSONL(encoder *json.Encoder, record Record) error {
+	return encoder.Encode(jsonlRecord{
+		ID:     &record.ID,
+		Vector: record.Vector,
+		Text:   record.Text,
+	})
+}
@@ -0,0 +1,147 @@
+package vectorfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	npyMagic = []byte("\x93NUMPY")
+
+	npyDescr   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+// npyReader reads a 2-D little-endian float32 matrix in C order, one row per
+// record.
+type npyReader struct {
+	r    *bufio.Reader
+	rows uint64
+	dim  int
+	n    uint64
+	buf  []byte
+}
+
+func newNpyReader(r *bufio.Reader) (*npyReader, error) {
+	prefix := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r, prefix); err != nil || !bytes.Equal(prefix[:len(npyMagic)], npyMagic) {
+		return nil, errors.New("not a .npy file")
+	}
+
+	var headerLen int
+	switch major := prefix[len(npyMagic)]; major {
+	case 1:
+		var size [2]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return nil, fmt.Errorf("truncated .npy header: %w", err)
+		}
+		headerLen = int(binary.LittleEndian.Uint16(size[:]))
+	case 2, 3:
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return nil, fmt.Errorf("truncated .npy header: %w", err)
+		}
+		headerLen = int(binary.LittleEndian.Uint32(size[:]))
+	default:
+		return nil, fmt.Errorf("unsupported .npy version %d", major)
+	}
+
+	// Headers are a few hundred bytes, a larger length means a corrupt file.
+	if headerLen > 1<<16 {
+		return nil, fmt.Errorf("invalid .npy header length %d", headerLen)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("truncated .npy header: %w", err)
+	}
+
+	descr := npyDescr.FindSubmatch(header)
+	if descr == nil || (string(descr[1]) != "<f4" && string(descr[1]) != "f4") {
+		return nil, fmt.Errorf("unsupported .npy header %q: only little-endian float32 ('<f4') is supported", header)
+	}
+	if fortran := npyFortran.FindSubmatch(header); fortran == nil || string(fortran[1]) != "False" {
+		return nil, errors.New("unsupported .npy file: only C order matrices are supported")
+	}
+	shape := npyShape.FindSubmatch(header)
+	if shape == nil {
+		return nil, fmt.Errorf("invalid .npy header %q: no shape", header)
+	}
+	var dims []uint64
+	for _, field := range strings.Split(string(shape[1]), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		dim, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid .npy shape (%s): %w", shape[1], err)
+		}
+		dims = append(dims, dim)
+	}
+	// An empty matrix, as written for no records, has no dimension either.
+	if len(dims) != 2 || (dims[1] == 0 && dims[0] > 0) {
+		return nil, fmt.Errorf("unsupported .npy shape (%s): expected a rows x dimension matrix", shape[1])
+	}
+	if dims[1] > MaxDimension {
+		return nil, fmt.Errorf("invalid .npy shape (%s): dimension %d is above %d", shape[1], dims[1], MaxDimension)
+	}
+
+	return &npyReader{r: r, rows: dims[0], dim: int(dims[1]), buf: make([]byte, 4*dims[1])}, nil
+}
+
+func (n *npyReader) read() (Record, error) {
+	if n.n == n.rows {
+		return Record{}, io.EOF
+	}
+	if _, err := io.ReadFull(n.r, n.buf); err != nil {
+		return Record{}, fmt.Errorf("record %d of %d: %w", n.n+1, n.rows, io.ErrUnexpectedEOF)
+	}
+
+	vector := make([]float32, n.dim)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(n.buf[i*4:]))
+	}
+	record := Record{ID: muopdbclient.NewDocIDFromUint64(n.n), Vector: vector}
+	n.n++
+	return record, nil
+}
+
+// npyHeaderLen is the length of the headers written, including the prelude.
+// Keeping it fixed lets a writer reserve the header before the number of rows
+// is known and fill it in once done.
+const npyHeaderLen = 128
+
+// npyHeader returns a version 1.0 header. It is padded with spaces and ends
+// with a newline so the data starts on a 64-byte boundary.
+func npyHeader(rows uint64, dim int) []byte {
+	dict := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d), }", rows, dim)
+	preludeLen := len(npyMagic) + 2 + 2
+	dict += strings.Repeat(" ", npyHeaderLen-preludeLen-len(dict)-1) + "\n"
+
+	header := append(append([]byte{}, npyMagic...), 1, 0, 0, 0)
+	binary.LittleEndian.PutUint16(header[len(npyMagic)+2:], uint16(len(dict)))
+	return append(header, dict...)
+}
+
+func writeNpyRow(w io.Writer, record Record, buf []byte) ([]byte, error) {
+	size := 4 * len(record.Vector)
+	if cap(buf) < size {
+		buf = make([]byte, size)
+	}
+	buf = buf[:size]
+	for i, value := range record.Vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(value))
+	}
+	_, err := w.Write(buf)
+	return buf, err
+}
@@ -0,0 +1,152 @@
+package vectorfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"io"
+	"math"
+	"os"
+)
+
+// vecsReader reads the TEXMEX formats: every vector is a little-endian int32
+// dimension followed by that many float32 (fvecs), uint8 (bvecs) or int32
+// (ivecs) components.
+type vecsReader struct {
+	format Format
+	r      *bufio.Reader
+	n      uint64
+	// dim is the dimension of the first record, which every other record must
+	// share.
+	dim int32
+	buf []byte
+}
+
+func newVecsReader(format Format, r *bufio.Reader) *vecsReader {
+	return &vecsReader{format: format, r: r}
+}
+
+func componentSize(format Format) int {
+	if format == FormatBvecs {
+		return 1
+	}
+	return 4
+}
+
+// next reads the raw components of the next record.
+func (v *vecsReader) next() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(v.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("record %d: truncated dimension", v.n+1)
+		}
+		return nil, err
+	}
+	dim := int32(binary.LittleEndian.Uint32(header[:]))
+	switch {
+	case dim <= 0 || dim > MaxDimension:
+		return nil, fmt.Errorf("record %d: invalid dimension %d, expected 1 to %d", v.n+1, dim, MaxDimension)
+	case v.n == 0:
+		v.dim = dim
+	case dim != v.dim:
+		return nil, fmt.Errorf("record %d: dimension %d differs from the dimension %d of the first record", v.n+1, dim, v.dim)
+	}
+
+	size := int(dim) * componentSize(v.format)
+	if cap(v.buf) < size {
+		v.buf = make([]byte, size)
+	}
+	buf := v.buf[:size]
+	if _, err := io.ReadFull(v.r, buf); err != nil {
+		return nil, fmt.Errorf("record %d: truncated after its dimension %d: %w", v.n+1, dim, io.ErrUnexpectedEOF)
+	}
+	v.n++
+	return buf, nil
+}
+
+func (v *vecsReader) read() (Record, error) {
+	buf, err := v.next()
+	if err != nil {
+		return Record{}, err
+	}
+
+	vector := make([]float32, len(buf)/componentSize(v.format))
+	for i := range vector {
+		switch v.format {
+		case FormatFvecs:
+			vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+		case FormatBvecs:
+			vector[i] = float32(buf[i])
+		case FormatIvecs:
+			vector[i] = float32(int32(binary.LittleEndian.Uint32(buf[i*4:])))
+		}
+	}
+	return Record{ID: muopdbclient.NewDocIDFromUint64(v.n - 1), Vector: vector}, nil
+}
+
+// LoadIvecs reads every record of an .ivecs file as the int32 values it
+// stores. Records hold float32 components, which round the integers above
+// 2^24, so use LoadIvecs for integers such as the neighbor ids of ground truth
+// files.
+func LoadIvecs(path string) ([][]int32, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	if format != FormatIvecs {
+		return nil, fmt.Errorf("%s: expected an .ivecs file", path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	v := newVecsReader(FormatIvecs, bufio.NewReaderSize(file, 1<<20))
+	var rows [][]int32
+	for {
+		buf, err := v.next()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		row := make([]int32, len(buf)/4)
+		for i := range row {
+			row[i] = int32(binary.LittleEndian.Uint32(buf[i*4:]))
+		}
+		rows = append(rows, row)
+	}
+}
+
+// writeVecs appends the record numbered n, from 1, to a vecs file.
+func writeVecs(format Format, w io.Writer, n uint64, record Record, buf []byte) ([]byte, error) {
+	size := 4 + len(record.Vector)*componentSize(format)
+	if cap(buf) < size {
+		buf = make([]byte, size)
+	}
+	buf = buf[:size]
+	binary.LittleEndian.PutUint32(buf, uint32(len(record.Vector)))
+
+	for i, value := range record.Vector {
+		switch format {
+		case FormatFvecs:
+			binary.LittleEndian.PutUint32(buf[4+i*4:], math.Float32bits(value))
+		case FormatBvecs:
+			if value != float32(math.Trunc(float64(value))) || value < 0 || value > math.MaxUint8 {
+				return buf, fmt.Errorf("record %d: component %v does not fit a bvecs byte", n, value)
+			}
+			buf[4+i] = byte(value)
+		case FormatIvecs:
+			if value != float32(math.Trunc(float64(value))) || value < math.MinInt32 || value > math.MaxInt32 {
+				return buf, fmt.Errorf("record %d: component %v is not an ivecs int32", n, value)
+			}
+			binary.LittleEndian.PutUint32(buf[4+i*4:], uint32(int32(value)))
+		}
+	}
+	_, err := w.Write(buf)
+	return buf, err
+}
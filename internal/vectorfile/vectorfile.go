@@ -0,0 +1,172 @@
+// Package vectorfile reads and writes embeddings in the formats other tools
+// use: the .fvecs/.bvecs/.ivecs files of the ANN benchmarks, NumPy .npy
+// matrices, JSON Lines and the gob files written by earlier versions of the
+// CLI. The format is picked from the file extension.
+package vectorfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatFvecs Format = "fvecs"
+	FormatBvecs Format = "bvecs"
+	FormatIvecs Format = "ivecs"
+	FormatNpy   Format = "npy"
+	FormatJSONL Format = "jsonl"
+	FormatGob   Format = "gob"
+)
+
+var extensions = map[string]Format{
+	".fvecs":  FormatFvecs,
+	".bvecs":  FormatBvecs,
+	".ivecs":  FormatIvecs,
+	".npy":    FormatNpy,
+	".jsonl":  FormatJSONL,
+	".ndjson": FormatJSONL,
+	".gob":    FormatGob,
+}
+
+// DetectFormat picks the format of a file from its extension.
+func DetectFormat(path string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	format, ok := extensions[ext]
+	if !ok {
+		return "", fmt.Errorf("unknown vector file extension %q, expected one of .fvecs, .bvecs, .ivecs, .npy, .jsonl or .gob", ext)
+	}
+	return format, nil
+}
+
+// MaxDimension bounds the dimension read from file headers, so a corrupt header
+// fails with an error instead of a huge allocation. It is far above the
+// dimension of any embedding model.
+const MaxDimension = 1 << 16
+
+// Record is one embedding. Only JSON Lines files carry ids and text, the
+// records of the other formats are numbered from zero in file order.
+type Record struct {
+	ID     muopdbclient.DocID
+	Vector []float32
+	Text   string
+}
+
+// recordReader yields the records of a file one at a time and returns io.EOF
+// after the last one.
+type recordReader interface {
+	read() (Record, error)
+}
+
+func newRecordReader(format Format, r *bufio.Reader) (recordReader, error) {
+	switch format {
+	case FormatFvecs, FormatBvecs, FormatIvecs:
+		return newVecsReader(format, r), nil
+	case FormatNpy:
+		return newNpyReader(r)
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	case FormatGob:
+		return newGobReader(r)
+	default:
+		return nil, fmt.Errorf("unsupported vector file format %q", format)
+	}
+}
+
+// Reader streams the records of a vector file, so files larger than memory can
+// be inserted.
+type Reader struct {
+	path    string
+	file    *os.File
+	records recordReader
+}
+
+// Open opens the file at path in the format of its extension.
+func Open(path string) (*Reader, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	records, err := newRecordReader(format, bufio.NewReaderSize(file, 1<<20))
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Reader{path: path, file: file, records: records}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one. Errors name the
+// file and the record or line they occurred at.
+func (r *Reader) Next() (Record, error) {
+	record, err := r.records.read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Record{}, fmt.Errorf("%s: %w", r.path, err)
+	}
+	return record, err
+}
+
+func (r *Reader) Path() string {
+	return r.path
+}
+
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// Load reads every record of the file at path. Prefer Open for large files.
+func Load(path string) ([]Record, error) {
+	reader, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var records []Record
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// Save writes the records to path in the format of its extension. Formats
+// without ids or text drop them.
+func Save(path string, records []Record) error {
+	writer, err := Create(path)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			writer.file.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Vectors returns the vectors of the records, in order.
+func Vectors(records []Record) [][]float32 {
+	vectors := make([][]float32, len(records))
+	for i, record := range records {
+		vectors[i] = record.Vector
+	}
+	return vectors
+}
@@ -0,0 +1,265 @@
+package vectorfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/TrungBui59/test_muopdb/internal/muopdbclient"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testRecords() []Record {
+	return []Record{
+		{ID: muopdbclient.NewDocIDFromUint64(10), Vector: []float32{1, 2, 3}, Text: "first"},
+		{ID: muopdbclient.DocID{Low: 11, High: 7}, Vector: []float32{0, 255, 17}, Text: "second"},
+		{ID: muopdbclient.NewDocIDFromUint64(12), Vector: []float32{4, 5, 6}},
+	}
+}
+
+// numbered drops the ids and text of the records, as formats without them do.
+func numbered(records []Record) []Record {
+	out := make([]Record, len(records))
+	for i, record := range records {
+		out[i] = Record{ID: muopdbclient.NewDocIDFromUint64(uint64(i)), Vector: record.Vector}
+	}
+	return out
+}
+
+func TestRoundTrip(t *testing.T) {
+	records := testRecords()
+	tests := []struct {
+		ext  string
+		want []Record
+	}{
+		{ext: ".fvecs", want: numbered(records)},
+		{ext: ".bvecs", want: numbered(records)},
+		{ext: ".ivecs", want: numbered(records)},
+		{ext: ".npy", want: numbered(records)},
+		{ext: ".jsonl", want: records},
+		{ext: ".ndjson", want: records},
+		{ext: ".gob", want: numbered(records)},
+	}
+	for _, test := range tests {
+		t.Run(test.ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vectors"+test.ext)
+			if err := Save(path, records); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, test.want) {
+				t.Errorf("loaded %+v, want %+v", loaded, test.want)
+			}
+		})
+	}
+}
+
+func TestRoundTripEmpty(t *testing.T) {
+	for _, ext := range []string{".fvecs", ".npy", ".jsonl", ".gob"} {
+		path := filepath.Join(t.TempDir(), "vectors"+ext)
+		if err := Save(path, nil); err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+		loaded, err := Load(path)
+		if err != nil || len(loaded) != 0 {
+			t.Errorf("%s: loaded %v, %v, want no records", ext, loaded, err)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	if format, err := DetectFormat("data/SIFT.FVECS"); err != nil || format != FormatFvecs {
+		t.Errorf("DetectFormat(SIFT.FVECS) = %q, %v, want fvecs", format, err)
+	}
+	if _, err := DetectFormat("vectors.csv"); err == nil {
+		t.Error("DetectFormat accepted a .csv file")
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		ext    string
+		vector []float32
+	}{
+		{ext: ".bvecs", vector: []float32{256}},
+		{ext: ".bvecs", vector: []float32{0.5}},
+		{ext: ".ivecs", vector: []float32{1.5}},
+	}
+	for _, test := range tests {
+		err := Save(filepath.Join(dir, "vectors"+test.ext), []Record{{Vector: []float32{1}}, {Vector: test.vector}})
+		if err == nil || !strings.Contains(err.Error(), "record 2") {
+			t.Errorf("saving %v as %s returned %v, want an error naming record 2", test.vector, test.ext, err)
+		}
+	}
+
+	err := Save(filepath.Join(dir, "vectors.npy"), []Record{{Vector: []float32{1, 2}}, {Vector: []float32{1}}})
+	if err == nil || !strings.Contains(err.Error(), "record 2 has dimension 1") {
+		t.Errorf("saving a ragged .npy returned %v", err)
+	}
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func vecsRecord(dim uint32, components ...float32) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, dim)
+	for _, c := range components {
+		b = binary.LittleEndian.AppendUint32(b, uint32(int32(c)))
+	}
+	return b
+}
+
+// readAll reads the file at path up to its first error.
+func readAll(t *testing.T, path string) ([]Record, error) {
+	t.Helper()
+	reader, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var records []Record
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    []byte
+		wantErr string
+	}{
+		{
+			name:    "truncated vector",
+			file:    "v.ivecs",
+			data:    append(vecsRecord(2, 1, 2), vecsRecord(2, 3)...),
+			wantErr: "record 2: truncated after its dimension 2",
+		},
+		{
+			name:    "truncated dimension",
+			file:    "v.ivecs",
+			data:    append(vecsRecord(1, 1), 0, 0),
+			wantErr: "record 2: truncated dimension",
+		},
+		{
+			name:    "corrupt dimension",
+			file:    "v.fvecs",
+			data:    append(vecsRecord(1, 1), vecsRecord(0x7fffffff)...),
+			wantErr: "record 2: invalid dimension 2147483647",
+		},
+		{
+			name:    "changing dimension",
+			file:    "v.ivecs",
+			data:    append(vecsRecord(1, 1), vecsRecord(2, 1, 2)...),
+			wantErr: "record 2: dimension 2 differs from the dimension 1 of the first record",
+		},
+		{
+			name:    "invalid json",
+			file:    "v.jsonl",
+			data:    []byte("{\"vector\": [1]}\n\n{\"vector\": [1,}\n"),
+			wantErr: "line 3:",
+		},
+		{
+			name:    "json without a vector",
+			file:    "v.jsonl",
+			data:    []byte("{\"id\": 1, \"text\": \"hi\"}\n"),
+			wantErr: "line 1: no vector",
+		},
+		{
+			name:    "negative json id",
+			file:    "v.jsonl",
+			data:    []byte("{\"id\": -1, \"vector\": [1]}\n"),
+			wantErr: "line 1: invalid id -1",
+		},
+		{
+			name:    "truncated npy",
+			file:    "v.npy",
+			data:    append(npyHeader(2, 2), make([]byte, 12)...),
+			wantErr: "record 2 of 2",
+		},
+		{
+			name:    "huge npy dimension",
+			file:    "v.npy",
+			data:    npyHeader(1, 1<<30),
+			wantErr: "dimension 1073741824 is above",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeFile(t, test.file, test.data)
+			_, err := readAll(t, path)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) || !strings.HasPrefix(err.Error(), path+": ") {
+				t.Errorf("reading returned %v, want an error of %s containing %q", err, path, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSONLIDs(t *testing.T) {
+	path := writeFile(t, "v.jsonl", []byte(`{"id": 42, "vector": [1]}
+{"id": "43", "vector": [2]}
+{"id": "00000000-0000-0000-0000-00000000002c", "vector": [3]}
+{"vector": [4], "text": "numbered by position"}
+`))
+	records, err := readAll(t, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []muopdbclient.DocID
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	want := []muopdbclient.DocID{
+		muopdbclient.NewDocIDFromUint64(42),
+		muopdbclient.NewDocIDFromUint64(43),
+		muopdbclient.NewDocIDFromUint64(44),
+		muopdbclient.NewDocIDFromUint64(3),
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+}
+
+func TestLoadIvecs(t *testing.T) {
+	data := binary.LittleEndian.AppendUint32(nil, 2)
+	data = binary.LittleEndian.AppendUint32(data, 16777217)
+	data = binary.LittleEndian.AppendUint32(data, uint32(0xffffffff))
+	path := writeFile(t, "neighbors.ivecs", data)
+
+	rows, err := LoadIvecs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int32{{16777217, -1}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("LoadIvecs = %v, want %v", rows, want)
+	}
+
+	truncated := writeFile(t, "truncated.ivecs", data[:8])
+	if _, err := LoadIvecs(truncated); err == nil || !strings.Contains(err.Error(), "record 1: truncated") {
+		t.Errorf("LoadIvecs of a truncated file returned %v", err)
+	}
+	if _, err := LoadIvecs(writeFile(t, "vectors.fvecs", data)); err == nil {
+		t.Error("LoadIvecs read an .fvecs file")
+	}
+}
@@ -0,0 +1,106 @@
+package vectorfile
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Writer streams records to a vector file. Records are written as they come,
+// except for gob files which can only be encoded at once on Close.
+type Writer struct {
+	path   string
+	format Format
+	file   *os.File
+	w      *bufio.Writer
+	n      uint64
+	dim    int
+	buf    []byte
+
+	jsonl   *json.Encoder
+	vectors [][]float32
+}
+
+// Create creates or truncates the file at path, in the format of its
+// extension.
+func Create(path string) (*Writer, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := &Writer{
+		path:   path,
+		format: format,
+		file:   file,
+		w:      bufio.NewWriterSize(file, 1<<20),
+	}
+
+	switch format {
+	case FormatJSONL:
+		writer.jsonl = json.NewEncoder(writer.w)
+	case FormatNpy:
+		// Reserved, the shape is only known on Close.
+		_, err = writer.w.Write(npyHeader(0, 0))
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("writing %s: %w", path, err)
+	}
+	return writer, nil
+}
+
+// Write appends a record. Formats without ids or text drop them.
+func (w *Writer) Write(record Record) error {
+	w.n++
+	var err error
+	switch w.format {
+	case FormatFvecs, FormatBvecs, FormatIvecs:
+		w.buf, err = writeVecs(w.format, w.w, w.n, record, w.buf)
+	case FormatNpy:
+		if w.n == 1 {
+			w.dim = len(record.Vector)
+		}
+		if len(record.Vector) != w.dim {
+			err = fmt.Errorf("record %d has dimension %d, a .npy matrix needs %d", w.n, len(record.Vector), w.dim)
+			break
+		}
+		w.buf, err = writeNpyRow(w.w, record, w.buf)
+	case FormatJSONL:
+		err = writeJSONL(w.jsonl, record)
+	case FormatGob:
+		w.vectors = append(w.vectors, record.Vector)
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", w.path, err)
+	}
+	return nil
+}
+
+// Close completes and closes the file. The file is incomplete if Close fails.
+func (w *Writer) Close() error {
+	var err error
+	switch w.format {
+	case FormatNpy:
+		if err = w.w.Flush(); err == nil {
+			_, err = w.file.WriteAt(npyHeader(w.n, w.dim), 0)
+		}
+	case FormatGob:
+		err = writeGob(w.w, w.vectors)
+	}
+	if err == nil {
+		err = w.w.Flush()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", w.path, err)
+	}
+	return nil
+}